@@ -0,0 +1,344 @@
+// Command bench measures the turns per second of the engines across the images in images/ and
+// numbers of threads, and writes a report as CSV or JSON. Run it from the root of the repository:
+//
+//	go run ./bench -turns 100 -threads 8 -format csv -o report.csv
+//
+// Every measurement is the median of -repeats runs, timed from the first turn starting to the last
+// turn completing, so image input and output aren't included. The remote engine is measured with
+// the first one to all of the servers given. Speedup is relative to the local engine with one
+// thread on the same image and efficiency is the speedup per thread, or per server for the remote
+// engine.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// Machine describes where the report was made, for comparing reports from different machines.
+type Machine struct {
+	OS        string
+	Arch      string
+	CPU       string
+	CPUs      int
+	GoVersion string
+	Hostname  string
+}
+
+// Result is the median measurement of one engine, image and number of threads.
+type Result struct {
+	Engine         string
+	Width, Height  int
+	Threads        int
+	Servers        int
+	Turns          int
+	Seconds        float64
+	TurnsPerSecond float64
+	Speedup        float64
+	Efficiency     float64
+}
+
+type Report struct {
+	Date    time.Time
+	Machine Machine
+	Repeats int
+	Results []Result
+}
+
+// size is the width and height of an image in images/.
+type size struct{ width, height int }
+
+var imageName = regexp.MustCompile(`^(\d+)x(\d+)\.pgm`)
+
+func main() {
+	turns := flag.Int("turns", 100, "Specify the number of turns each run processes.")
+	maxThreads := flag.Int("threads", runtime.NumCPU(), "Measure the local engine with 1 to this many threads.")
+	repeats := flag.Int("repeats", 3, "Specify the number of runs each measurement is the median of.")
+	sizes := flag.String("sizes", "", "Only measure these comma separated sizes, such as 64x64,512x512. Defaults to every image in images/.")
+	engines := flag.String("engines", gol.LocalEngine, "Measure these comma separated engines, such as local,remote.")
+	servers := flag.String("servers", gol.DefaultServer, "Measure the remote engine with 1 to all of these comma separated server addresses.")
+	format := flag.String("format", "csv", "Write the report as csv or json.")
+	outPath := flag.String("o", "-", "Write the report to this file. Use - for stdout.")
+	flag.Parse()
+
+	if *format != "csv" && *format != "json" {
+		fail(fmt.Errorf("unknown format %q", *format))
+	}
+	// The engine prints progress to stdout, so keep stdout for the report and send the rest to stderr.
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	if *outPath != "-" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			fail(err)
+		}
+		defer file.Close()
+		out = file
+	}
+	images, err := findImages("images", *sizes)
+	if err != nil {
+		fail(err)
+	}
+	outDir, err := os.MkdirTemp("", "gol-bench")
+	if err != nil {
+		fail(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	report := Report{Date: time.Now().UTC(), Machine: machine(), Repeats: *repeats}
+	for _, engine := range strings.Split(*engines, ",") {
+		engine = strings.TrimSpace(engine)
+		for _, image := range images {
+			p := gol.Params{
+				Turns:          *turns,
+				Threads:        1,
+				ImageWidth:     image.width,
+				ImageHeight:    image.height,
+				OutDir:         outDir,
+				ReportInterval: time.Hour,
+				Engine:         engine,
+			}
+			for _, p := range sweep(p, *maxThreads, strings.Split(*servers, ",")) {
+				if engine == gol.RemoteEngine {
+					fmt.Fprintf(os.Stderr, "%v %vx%v %v servers\n", engine, p.ImageWidth, p.ImageHeight, len(p.Servers))
+				} else {
+					fmt.Fprintf(os.Stderr, "%v %vx%v %v threads\n", engine, p.ImageWidth, p.ImageHeight, p.Threads)
+				}
+				result, err := measure(p, *repeats)
+				if err != nil {
+					fail(err)
+				}
+				report.Results = append(report.Results, result)
+			}
+		}
+	}
+	speedUp(report.Results)
+
+	w := bufio.NewWriter(out)
+	if *format == "json" {
+		err = writeJSON(w, report)
+	} else {
+		err = writeCSV(w, report)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// sweep returns the params to measure for p's engine: the local engine with 1 to maxThreads
+// threads, or the remote engine with one thread and the first 1 to all of servers.
+func sweep(p gol.Params, maxThreads int, servers []string) []gol.Params {
+	var sweep []gol.Params
+	if p.Engine == gol.RemoteEngine {
+		for n := 1; n <= len(servers); n++ {
+			p.Threads, p.Servers = 1, servers[:n]
+			sweep = append(sweep, p)
+		}
+		return sweep
+	}
+	for p.Threads = 1; p.Threads <= maxThreads; p.Threads++ {
+		sweep = append(sweep, p)
+	}
+	return sweep
+}
+
+// findImages returns the sizes of the images in dir, smallest first, keeping only those in filter
+// if it isn't empty.
+func findImages(dir, filter string) ([]size, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool)
+	for _, s := range strings.Split(filter, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			wanted[s] = true
+		}
+	}
+	seen := make(map[size]bool)
+	var sizes []size
+	for _, entry := range entries {
+		match := imageName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		width, _ := strconv.Atoi(match[1])
+		height, _ := strconv.Atoi(match[2])
+		s := size{width, height}
+		if seen[s] || len(wanted) > 0 && !wanted[match[1]+"x"+match[2]] {
+			continue
+		}
+		seen[s] = true
+		sizes = append(sizes, s)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no images to measure in %v", dir)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].width*sizes[i].height < sizes[j].width*sizes[j].height
+	})
+	return sizes, nil
+}
+
+// measure runs p repeats times and returns the median time taken.
+func measure(p gol.Params, repeats int) (Result, error) {
+	var times []float64
+	for i := 0; i < repeats || i == 0; i++ {
+		elapsed, err := run(p)
+		if err != nil {
+			return Result{}, err
+		}
+		times = append(times, elapsed)
+	}
+	seconds := median(times)
+	result := Result{
+		Engine:  p.Engine,
+		Width:   p.ImageWidth,
+		Height:  p.ImageHeight,
+		Threads: p.Threads,
+		Servers: len(p.Servers),
+		Turns:   p.Turns,
+		Seconds: seconds,
+	}
+	if seconds > 0 {
+		result.TurnsPerSecond = float64(p.Turns) / seconds
+	}
+	return result, nil
+}
+
+// median returns the median of times, which it sorts.
+func median(times []float64) float64 {
+	sort.Float64s(times)
+	median := times[len(times)/2]
+	if len(times)%2 == 0 {
+		median = (times[len(times)/2-1] + median) / 2
+	}
+	return median
+}
+
+// run runs p and returns the seconds between the first turn starting and the last turn completing.
+// Events are unbuffered so that they're timed as they're sent rather than when they're caught up with.
+func run(p gol.Params) (float64, error) {
+	events := make(chan gol.Event)
+	done := make(chan error, 1)
+	go func() {
+		_, err := gol.RunContext(context.Background(), p, gol.WithEvents(events))
+		done <- err
+	}()
+	var start, end time.Time
+	for event := range events {
+		switch e := event.(type) {
+		case gol.StateChange:
+			if e.NewState == gol.Executing && start.IsZero() {
+				start = time.Now()
+			}
+		case gol.TurnComplete:
+			if e.CompletedTurns == p.Turns {
+				end = time.Now()
+			}
+		}
+	}
+	if err := <-done; err != nil {
+		return 0, err
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(start).Seconds(), nil
+}
+
+// speedUp fills in the speedup and efficiency of every result relative to the local engine with
+// one thread on the same image.
+func speedUp(results []Result) {
+	baselines := make(map[size]float64)
+	for _, r := range results {
+		if r.Engine == gol.LocalEngine && r.Threads == 1 {
+			baselines[size{r.Width, r.Height}] = r.TurnsPerSecond
+		}
+	}
+	for i, r := range results {
+		baseline := baselines[size{r.Width, r.Height}]
+		if baseline == 0 {
+			continue
+		}
+		workers := r.Threads
+		if r.Engine == gol.RemoteEngine {
+			workers = r.Servers
+		}
+		results[i].Speedup = r.TurnsPerSecond / baseline
+		results[i].Efficiency = results[i].Speedup / float64(workers)
+	}
+}
+
+func machine() Machine {
+	m := Machine{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+	m.Hostname, _ = os.Hostname()
+	if cpuinfo, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(cpuinfo), "\n") {
+			if strings.HasPrefix(line, "model name") {
+				m.CPU = strings.TrimSpace(line[strings.Index(line, ":")+1:])
+				break
+			}
+		}
+	}
+	return m
+}
+
+func writeJSON(w io.Writer, report Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// writeCSV writes the results as CSV, after the date and machine as comment lines starting with #.
+func writeCSV(w io.Writer, report Report) error {
+	m := report.Machine
+	fmt.Fprintf(w, "# date %v\n", report.Date.Format(time.RFC3339))
+	fmt.Fprintf(w, "# machine %v/%v, %v CPUs, %v, %v, host %v\n", m.OS, m.Arch, m.CPUs, m.CPU, m.GoVersion, m.Hostname)
+	fmt.Fprintf(w, "# median of %v runs\n", report.Repeats)
+	c := csv.NewWriter(w)
+	c.Write([]string{"engine", "width", "height", "threads", "servers", "turns", "seconds", "turns_per_second", "speedup", "efficiency"})
+	for _, r := range report.Results {
+		c.Write([]string{
+			r.Engine,
+			strconv.Itoa(r.Width),
+			strconv.Itoa(r.Height),
+			strconv.Itoa(r.Threads),
+			strconv.Itoa(r.Servers),
+			strconv.Itoa(r.Turns),
+			strconv.FormatFloat(r.Seconds, 'f', 6, 64),
+			strconv.FormatFloat(r.TurnsPerSecond, 'f', 2, 64),
+			strconv.FormatFloat(r.Speedup, 'f', 3, 64),
+			strconv.FormatFloat(r.Efficiency, 'f', 3, 64),
+		})
+	}
+	c.Flush()
+	return c.Error()
+}
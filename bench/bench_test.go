@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestFindImages tests that images are found by their names, once per size, smallest first, and
+// filtered by size.
+func TestFindImages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"64x64.pgm", "16x16.pgm", "16x16.pgm.gz", "512x128.pgm", "notes.txt", "x16.pgm"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sizes, err := findImages(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []size{{16, 16}, {64, 64}, {512, 128}}; !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("Expected %v, got %v", expected, sizes)
+	}
+
+	sizes, err = findImages(dir, " 512x128, 16x16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []size{{16, 16}, {512, 128}}; !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("Expected %v with a filter, got %v", expected, sizes)
+	}
+
+	if _, err = findImages(dir, "32x32"); err == nil {
+		t.Error("Expected an error when no image matches the filter")
+	}
+	if _, err = findImages(filepath.Join(dir, "missing"), ""); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+// TestMedian tests the median of odd and even numbers of times given in any order.
+func TestMedian(t *testing.T) {
+	for _, test := range []struct {
+		times    []float64
+		expected float64
+	}{
+		{[]float64{3}, 3},
+		{[]float64{5, 1, 3}, 3},
+		{[]float64{4, 1, 3, 2}, 2.5},
+		{[]float64{10, 1, 2, 9, 3}, 3},
+	} {
+		if median := median(append([]float64(nil), test.times...)); median != test.expected {
+			t.Errorf("Expected the median of %v to be %v, got %v", test.times, test.expected, median)
+		}
+	}
+}
+
+// TestSpeedUp tests that speedup is relative to the local engine with one thread on the same image,
+// and efficiency is per thread, or per server for the remote engine.
+func TestSpeedUp(t *testing.T) {
+	results := []Result{
+		{Engine: gol.LocalEngine, Width: 16, Height: 16, Threads: 1, TurnsPerSecond: 100},
+		{Engine: gol.LocalEngine, Width: 16, Height: 16, Threads: 4, TurnsPerSecond: 300},
+		{Engine: gol.RemoteEngine, Width: 16, Height: 16, Threads: 1, Servers: 2, TurnsPerSecond: 50},
+		{Engine: gol.LocalEngine, Width: 64, Height: 64, Threads: 1, TurnsPerSecond: 10},
+		{Engine: gol.LocalEngine, Width: 64, Height: 64, Threads: 2, TurnsPerSecond: 20},
+		{Engine: gol.RemoteEngine, Width: 512, Height: 512, Threads: 1, Servers: 1, TurnsPerSecond: 5},
+	}
+	speedUp(results)
+	expected := []struct{ speedup, efficiency float64 }{
+		{1, 1},
+		{3, 0.75},
+		{0.5, 0.25},
+		{1, 1},
+		{2, 1},
+		// There is no local baseline for this image.
+		{0, 0},
+	}
+	for i, r := range results {
+		if r.Speedup != expected[i].speedup || r.Efficiency != expected[i].efficiency {
+			t.Errorf("Result %v: expected a speedup of %v and efficiency of %v, got %v and %v",
+				i, expected[i].speedup, expected[i].efficiency, r.Speedup, r.Efficiency)
+		}
+	}
+}
+
+// TestSweep tests that the local engine is measured with every number of threads and the remote
+// engine with every number of servers.
+func TestSweep(t *testing.T) {
+	servers := []string{"a:8030", "b:8030", "c:8030"}
+	assertSweep := func(name string, sweep []gol.Params, threads []int, servers [][]string) {
+		if len(sweep) != len(threads) {
+			t.Fatalf("Expected %v %v measurements, got %v", len(threads), name, len(sweep))
+		}
+		for i, p := range sweep {
+			if p.Threads != threads[i] || !reflect.DeepEqual(p.Servers, servers[i]) {
+				t.Errorf("Expected %v measurement %v with %v threads and servers %v, got %v and %v",
+					name, i, threads[i], servers[i], p.Threads, p.Servers)
+			}
+		}
+	}
+	local := sweep(gol.Params{Engine: gol.LocalEngine}, 3, servers)
+	assertSweep("local", local, []int{1, 2, 3}, [][]string{nil, nil, nil})
+
+	remote := sweep(gol.Params{Engine: gol.RemoteEngine}, 8, servers)
+	assertSweep("remote", remote, []int{1, 1, 1}, [][]string{servers[:1], servers[:2], servers})
+}
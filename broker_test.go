@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBroker tests that every subscriber receives the event stream according to its policy and that
+// subscribers which don't read can't stall the publisher.
+func TestBroker(t *testing.T) {
+	const n = 10000
+	broker := gol.NewBroker()
+	slow := broker.Subscribe(10, gol.Unbounded)
+	fast := broker.Subscribe(0, gol.Unbounded)
+	dropping := broker.Subscribe(1, gol.Drop)
+
+	events := make(chan gol.Event)
+	brokerDone := make(chan bool)
+	go func() {
+		broker.Run(events)
+		brokerDone <- true
+	}()
+
+	fastTurns := make(chan []int)
+	go func() {
+		var turns []int
+		for event := range fast.Events {
+			turns = append(turns, event.GetCompletedTurns())
+		}
+		fastTurns <- turns
+	}()
+
+	timeout(t, 5*time.Second, func() {
+		for turn := 1; turn <= n; turn++ {
+			events <- gol.TurnComplete{CompletedTurns: turn}
+		}
+		close(events)
+		<-brokerDone
+	}, "Publishing was stalled by a subscriber that isn't reading")
+
+	assertInOrder := func(name string, turns []int) {
+		assert(t, len(turns) == n, "%v subscriber received %v events, expected %v", name, len(turns), n)
+		for i, turn := range turns {
+			if turn != i+1 {
+				t.Errorf("ERROR: %v subscriber received turn %v at position %v", name, turn, i)
+				return
+			}
+		}
+	}
+	assertInOrder("Fast", <-fastTurns)
+
+	var slowTurns []int
+	for event := range slow.Events {
+		slowTurns = append(slowTurns, event.GetCompletedTurns())
+	}
+	assertInOrder("Slow", slowTurns)
+
+	var droppingTurns []int
+	for event := range dropping.Events {
+		droppingTurns = append(droppingTurns, event.GetCompletedTurns())
+	}
+	received := len(droppingTurns)
+	assert(t, received+int(dropping.Dropped()) == n,
+		"Dropping subscriber received %v and dropped %v events, expected %v in total", received, dropping.Dropped(), n)
+	assert(t, received <= 2, "Dropping subscriber received %v events with a buffer of 1 and no reader", received)
+	assert(t, received > 0 && droppingTurns[received-1] == n,
+		"Dropping subscriber should still receive the latest turn, received %v", droppingTurns)
+
+	late := broker.Subscribe(1, gol.Unbounded)
+	_, ok := <-late.Events
+	assert(t, !ok, "Subscribing after the stream ended should return a closed subscription")
+}
+
+// TestBrokerDropFlips tests that a subscriber which drops events while it falls behind still ends up
+// with the world that was published, and with the latest turn.
+func TestBrokerDropFlips(t *testing.T) {
+	const width, height, turns = 16, 16, 2000
+	broker := gol.NewBroker()
+	dropping := broker.Subscribe(4, gol.Drop)
+	events := make(chan gol.Event)
+	go broker.Run(events)
+
+	var expected [height][width]bool
+	go func() {
+		rng := rand.New(rand.NewSource(1))
+		for turn := 1; turn <= turns; turn++ {
+			for i := rng.Intn(5); i > 0; i-- {
+				cell := util.Cell{X: rng.Intn(width), Y: rng.Intn(height)}
+				events <- gol.CellFlipped{CompletedTurns: turn - 1, Cell: cell}
+				expected[cell.Y][cell.X] = !expected[cell.Y][cell.X]
+			}
+			events <- gol.AliveCellsCount{CompletedTurns: turn}
+			events <- gol.TurnComplete{CompletedTurns: turn}
+		}
+		close(events)
+	}()
+
+	var world [height][width]bool
+	lastTurn := 0
+	for event := range dropping.Events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			world[e.Cell.Y][e.Cell.X] = !world[e.Cell.Y][e.Cell.X]
+		case gol.CellsFlipped:
+			for _, cell := range e.Cells {
+				world[cell.Y][cell.X] = !world[cell.Y][cell.X]
+			}
+		case gol.TurnComplete:
+			lastTurn = e.CompletedTurns
+			time.Sleep(10 * time.Microsecond)
+		}
+	}
+	assert(t, dropping.Dropped() > 0, "Expected a slow subscriber to drop some events")
+	assert(t, lastTurn == turns, "Expected the last turn received to be %v, got %v", turns, lastTurn)
+	assert(t, world == expected, "The world followed by a dropping subscriber differs from the one published")
+}
+
+// TestBrokerDropControl tests that a subscriber which drops events while its buffer is full still
+// receives every event other than flips, turns and reports, in the order they were published.
+func TestBrokerDropControl(t *testing.T) {
+	broker := gol.NewBroker()
+	dropping := broker.Subscribe(1, gol.Drop)
+	events := make(chan gol.Event)
+	brokerDone := make(chan bool)
+	go func() {
+		broker.Run(events)
+		brokerDone <- true
+	}()
+
+	cell := util.Cell{X: 1, Y: 2}
+	control := []gol.Event{
+		gol.StateChange{CompletedTurns: 100, NewState: gol.Paused},
+		gol.ErrorOccurred{CompletedTurns: 100, Err: errors.New("error")},
+		gol.StateChange{CompletedTurns: 200, NewState: gol.Executing},
+		gol.FinalTurnComplete{CompletedTurns: 300},
+		gol.StateChange{CompletedTurns: 300, NewState: gol.Quitting},
+	}
+	timeout(t, 5*time.Second, func() {
+		next := 0
+		for turn := 1; turn <= 300; turn++ {
+			events <- gol.CellFlipped{CompletedTurns: turn - 1, Cell: cell}
+			events <- gol.AliveCellsCount{CompletedTurns: turn, CellsCount: turn % 2}
+			events <- gol.TurnComplete{CompletedTurns: turn}
+			for next < len(control) && control[next].GetCompletedTurns() == turn {
+				events <- control[next]
+				next++
+			}
+		}
+		close(events)
+		<-brokerDone
+	}, "Publishing was stalled by a subscriber that isn't reading")
+
+	var received []gol.Event
+	alive := false
+	lastTurn := 0
+	for event := range dropping.Events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			alive = !alive
+		case gol.CellsFlipped:
+			for range e.Cells {
+				alive = !alive
+			}
+		case gol.TurnComplete:
+			lastTurn = e.CompletedTurns
+		case gol.AliveCellsCount, gol.WorldStats:
+		default:
+			received = append(received, event)
+		}
+	}
+	assert(t, dropping.Dropped() > 0, "Expected a subscriber that isn't reading to drop some events")
+	assert(t, len(received) == len(control), "Expected %v control events, received %v", len(control), received)
+	for i := 0; i < len(received) && i < len(control); i++ {
+		assert(t, received[i].String() == control[i].String() &&
+			received[i].GetCompletedTurns() == control[i].GetCompletedTurns(),
+			"Expected control event %v to be %v, received %v", i, control[i], received[i])
+	}
+	assert(t, !alive, "The cell flipped every turn should end up dead after an even number of turns")
+	assert(t, lastTurn == 300, "Expected the last turn received to be 300, got %v", lastTurn)
+}
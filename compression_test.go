@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestCompression tests that gzip input images are read and that output images are written in each container.
+func TestCompression(t *testing.T) {
+	p := gol.Params{Turns: 0, Threads: 1, ImageWidth: 9, ImageHeight: 9}
+	blinker := []util.Cell{{X: 3, Y: 4}, {X: 4, Y: 4}, {X: 5, Y: 4}}
+
+	image := make([]byte, p.ImageWidth*p.ImageHeight)
+	for _, cell := range blinker {
+		image[cell.Y*p.ImageWidth+cell.X] = 255
+	}
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = fmt.Fprintf(gz, "P5\n%v %v\n255\n", p.ImageWidth, p.ImageHeight)
+	_, _ = gz.Write(image)
+	util.Check(gz.Close())
+
+	path := "images/9x9.pgm.gz"
+	util.Check(os.WriteFile(path, compressed.Bytes(), 0644))
+	defer os.Remove(path)
+
+	for _, compression := range []string{gol.NoCompression, gol.Gzip, gol.Zlib} {
+		p.Compression = compression
+		t.Run(fmt.Sprintf("%q", compression), func(t *testing.T) {
+			emptyOutFolder()
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			for event := range events {
+				if e, ok := event.(gol.ErrorOccurred); ok {
+					t.Fatal(e)
+				}
+			}
+
+			out := "out/9x9x0.pgm"
+			if compression != gol.NoCompression {
+				out += "." + compression
+			}
+			file, err := os.Open(out)
+			util.Check(err)
+			defer file.Close()
+
+			var reader io.Reader = file
+			switch compression {
+			case gol.Gzip:
+				reader, err = gzip.NewReader(file)
+			case gol.Zlib:
+				reader, err = zlib.NewReader(file)
+			}
+			util.Check(err)
+			data, err := io.ReadAll(reader)
+			util.Check(err)
+
+			plain := "out/plain.pgm"
+			util.Check(os.WriteFile(plain, data, 0644))
+			assertEqualBoard(t, readAliveCells(plain, p.ImageWidth, p.ImageHeight), blinker, p)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestRunContext tests that cancelling a run stops it promptly, closes the events channel without
+// reporting an error, returns the cancellation error and leaves no goroutines behind.
+func TestRunContext(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p := gol.Params{
+		Turns:       100000000,
+		Threads:     8,
+		ImageWidth:  512,
+		ImageHeight: 512,
+		Soup:        &gol.Soup{Seed: 1},
+	}
+	events := make(chan gol.Event, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	var world [][]byte
+	go func() {
+		var err error
+		world, err = gol.RunContext(ctx, p, gol.WithEvents(events))
+		done <- err
+	}()
+
+	time.AfterFunc(500*time.Millisecond, cancel)
+	for event := range events {
+		if e, ok := event.(gol.ErrorOccurred); ok {
+			t.Fatalf("Cancellation reported as an error: %v", e.Err)
+		}
+	}
+
+	select {
+	case err := <-done:
+		assert(t, err == context.Canceled, "Expected %v, got %v", context.Canceled, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunContext did not return after its context was cancelled")
+	}
+	assert(t, len(world) == p.ImageHeight, "Expected the last world to be returned, got %v rows", len(world))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert(t, runtime.NumGoroutine() <= before, "%v goroutines leaked", runtime.NumGoroutine()-before)
+}
+
+// TestRunContextUnread tests that a run whose events stop being read still returns once it is
+// cancelled, for worlds generated as a soup and read from an image.
+func TestRunContextUnread(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for _, p := range []gol.Params{
+		{Turns: 100000000, Threads: 2, ImageWidth: 64, ImageHeight: 64, Soup: &gol.Soup{Seed: 1}},
+		{Turns: 100000000, Threads: 2, ImageWidth: 512, ImageHeight: 512},
+	} {
+		events := make(chan gol.Event)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			_, err := gol.RunContext(ctx, p, gol.WithEvents(events))
+			done <- err
+		}()
+		<-events
+		cancel()
+		select {
+		case err := <-done:
+			assert(t, err == context.Canceled, "Expected %v, got %v", context.Canceled, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("RunContext did not return after its context was cancelled while nothing read its events")
+		}
+		for range events {
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert(t, runtime.NumGoroutine() <= before, "%v goroutines leaked", runtime.NumGoroutine()-before)
+}
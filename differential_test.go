@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// differentialTurns is the number of turns each engine is checked for on each world.
+const differentialTurns = 30
+
+// engineVariants returns every engine configuration to check against util.ReferenceStep: the
+// local engine with thread counts that do and don't divide the height, including more threads than
+// rows, and the remote engine with one to three servers started for the test. There is no packed
+// engine in this tree; any new engine should be added here.
+func engineVariants(t *testing.T) []gol.Params {
+	var variants []gol.Params
+	for _, threads := range []int{1, 2, 3, 5, 8, 32} {
+		variants = append(variants, gol.Params{Engine: gol.LocalEngine, Threads: threads})
+	}
+	servers := startServers(t, 3)
+	for n := 1; n <= len(servers); n++ {
+		variants = append(variants, gol.Params{Engine: gol.RemoteEngine, Threads: 1, Servers: servers[:n]})
+	}
+	return variants
+}
+
+// TestDifferential steps random soups under a range of rules with every engine variant and checks
+// that every turn is bit-identical to util.ReferenceStep, that the cells reported as flipped are
+// exactly those that changed and that the world passed in is left unmodified.
+func TestDifferential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	rules := []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B1357/S1357", "B0/S8", "B012345678/S012345678"}
+	for i := 0; i < 3; i++ {
+		rules = append(rules, randomRule(rng).String())
+	}
+	sizes := []struct{ width, height int }{{16, 16}, {23, 9}, {7, 40}, {64, 64}}
+
+	for _, variant := range engineVariants(t) {
+		name := fmt.Sprintf("%v %v threads", variant.Engine, variant.Threads)
+		if variant.Engine == gol.RemoteEngine {
+			name = fmt.Sprintf("%v %v servers", variant.Engine, len(variant.Servers))
+		}
+		t.Run(name, func(t *testing.T) {
+			for i, rule := range rules {
+				size := sizes[i%len(sizes)]
+				p := variant
+				p.ImageWidth, p.ImageHeight, p.Rule = size.width, size.height, rule
+				seed := int64(i + 1)
+				world := randomWorld(rand.New(rand.NewSource(seed)), p.ImageWidth, p.ImageHeight, 0.1+0.1*float64(i%5))
+				if !crossCheck(t, p, world) {
+					t.Logf("Diverged on a %vx%v soup with seed %v under %v", p.ImageWidth, p.ImageHeight, seed, rule)
+				}
+			}
+		})
+	}
+}
+
+// crossCheck steps world for differentialTurns turns with the engine selected by p and with the
+// reference stepper, reporting the first turn and cell at which they diverge.
+func crossCheck(t *testing.T, p gol.Params, world [][]byte) bool {
+	rule, err := util.ParseRule(p.Rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := gol.NewEngine(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	expected := copyWorld(world)
+	for turn := 1; turn <= differentialTurns; turn++ {
+		next, flipped, err := engine.Step(context.Background(), world)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reference := util.ReferenceStep(expected, rule)
+
+		if cell, ok := firstDifference(world, expected); ok {
+			t.Errorf("%v: turn %v modified the world passed in at %v", p.Rule, turn, cell)
+			return false
+		}
+		if cell, ok := firstDifference(next, reference); ok {
+			t.Errorf("%v: turn %v first diverges from the reference at %v\n%v", p.Rule, turn, cell,
+				util.AliveCellsToString(aliveCells(next), aliveCells(reference), p.ImageWidth, p.ImageHeight))
+			return false
+		}
+		changed := make(map[util.Cell]bool)
+		for y := range reference {
+			for x := range reference[y] {
+				if reference[y][x] != expected[y][x] {
+					changed[util.Cell{X: x, Y: y}] = true
+				}
+			}
+		}
+		for _, cell := range flipped {
+			if !changed[cell] {
+				t.Errorf("%v: turn %v reported %v as flipped, but it didn't change or was reported twice", p.Rule, turn, cell)
+				return false
+			}
+			delete(changed, cell)
+		}
+		for cell := range changed {
+			t.Errorf("%v: turn %v didn't report %v as flipped", p.Rule, turn, cell)
+			return false
+		}
+		world, expected = next, reference
+	}
+	return true
+}
+
+// firstDifference returns the first cell, in row order, that differs between the worlds.
+func firstDifference(given, expected [][]byte) (util.Cell, bool) {
+	for y := range expected {
+		for x := range expected[y] {
+			if given[y][x] != expected[y][x] {
+				return util.Cell{X: x, Y: y}, true
+			}
+		}
+	}
+	return util.Cell{}, false
+}
+
+func randomWorld(rng *rand.Rand, width, height int, density float64) [][]byte {
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+		for x := range world[y] {
+			if rng.Float64() < density {
+				world[y][x] = 255
+			}
+		}
+	}
+	return world
+}
+
+// randomRule returns a rule with each birth and survival count chosen at random.
+func randomRule(rng *rand.Rand) util.Rule {
+	var rule util.Rule
+	for n := range rule.Birth {
+		rule.Birth[n] = rng.Intn(3) == 0
+		rule.Survive[n] = rng.Intn(3) == 0
+	}
+	return rule
+}
+
+func copyWorld(world [][]byte) [][]byte {
+	copied := make([][]byte, len(world))
+	for y := range world {
+		copied[y] = append([]byte(nil), world[y]...)
+	}
+	return copied
+}
+
+func aliveCells(world [][]byte) []util.Cell {
+	var cells []util.Cell
+	for y := range world {
+		for x := range world[y] {
+			if world[y][x] == 255 {
+				cells = append(cells, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
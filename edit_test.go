@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestEdits tests that the world can be cleared, randomised and edited while paused, and that the
+// next turn is computed from the edited world.
+func TestEdits(t *testing.T) {
+	p := gol.Params{Turns: 100000000, Threads: 2, ImageWidth: 16, ImageHeight: 16}
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune, 10)
+	edits := make(chan gol.Edit, 10)
+	go gol.RunContext(context.Background(), p, gol.WithEvents(events), gol.WithKeyPresses(keyPresses), gol.WithEdits(edits))
+
+	// next returns the next event other than TurnComplete, failing if none arrives in time.
+	next := func() gol.Event {
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					t.Fatal("The events channel was closed early")
+				}
+				if _, ok := event.(gol.TurnComplete); !ok {
+					return event
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("No event was sent in 2 seconds")
+			}
+		}
+	}
+	flipped := func() []util.Cell {
+		for {
+			if e, ok := next().(gol.CellsFlipped); ok {
+				return e.Cells
+			}
+		}
+	}
+
+	initial := flipped()
+	keyPresses <- 'p'
+	for {
+		if e, ok := next().(gol.StateChange); ok && e.NewState == gol.Paused {
+			break
+		}
+	}
+
+	keyPresses <- 'r'
+	assert(t, len(flipped()) > 0, "Randomising the world flipped no cells")
+	keyPresses <- 'c'
+	flipped()
+	keyPresses <- 'c'
+	keyPresses <- 'r'
+	assert(t, len(flipped()) > 0, "Randomising the world flipped no cells")
+	keyPresses <- 'c'
+	flipped()
+
+	blinker := []util.Cell{{X: 5, Y: 5}, {X: 6, Y: 5}, {X: 7, Y: 5}}
+	for _, cell := range blinker {
+		edits <- gol.Edit{Cell: cell, Alive: true}
+		cells := flipped()
+		assert(t, len(cells) == 1 && cells[0] == cell, "Expected %v to flip, got %v", cell, cells)
+	}
+	edits <- gol.Edit{Cell: blinker[0], Alive: true}
+	edits <- gol.Edit{Cell: util.Cell{X: 6, Y: 5}, Alive: false}
+	cells := flipped()
+	assert(t, len(cells) == 1 && cells[0] == blinker[1], "Expected only %v to flip, got %v", blinker[1], cells)
+	edits <- gol.Edit{Cell: blinker[1], Alive: true}
+	flipped()
+
+	// The blinker turns vertical in the first turn after resuming.
+	keyPresses <- 'p'
+	cells = flipped()
+	expected := map[util.Cell]bool{{X: 5, Y: 5}: true, {X: 7, Y: 5}: true, {X: 6, Y: 4}: true, {X: 6, Y: 6}: true}
+	assert(t, len(cells) == len(expected), "Expected %v cells to flip, got %v", len(expected), cells)
+	for _, cell := range cells {
+		assert(t, expected[cell], "Expected %v not to flip", cell)
+	}
+	assert(t, len(initial) > 0, "The initial world was not sent")
+
+	keyPresses <- 'q'
+	for range events {
+	}
+}
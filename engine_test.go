@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestLocalEngine tests that the local engine gives the same result for any number of threads,
+// including more threads than rows, on a world that isn't square, and that the cells flipped by
+// every turn add up to the final world.
+func TestLocalEngine(t *testing.T) {
+	var expected []util.Cell
+	density := 0.3
+	for _, threads := range []int{1, 2, 3, 8, 31} {
+		p := gol.Params{
+			Turns:       50,
+			Threads:     threads,
+			ImageWidth:  48,
+			ImageHeight: 24,
+			Soup:        &gol.Soup{Density: &density, Seed: 7},
+		}
+		events := make(chan gol.Event, 1000)
+		go gol.Run(p, events, nil)
+
+		world := make(map[util.Cell]bool)
+		turns := 0
+		var final []util.Cell
+		for event := range events {
+			switch e := event.(type) {
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					world[cell] = !world[cell]
+				}
+			case gol.TurnComplete:
+				turns++
+			case gol.FinalTurnComplete:
+				final = e.Alive
+			case gol.ErrorOccurred:
+				t.Fatal(e)
+			}
+		}
+
+		assert(t, turns == p.Turns, "%v threads: expected %v TurnComplete events, got %v", threads, p.Turns, turns)
+		var flipped []util.Cell
+		for cell, alive := range world {
+			if alive {
+				flipped = append(flipped, cell)
+			}
+		}
+		assertEqualBoard(t, flipped, final, p)
+		if expected == nil {
+			expected = final
+		} else {
+			assertEqualBoard(t, final, expected, p)
+		}
+	}
+}
+
+// TestUnknownEngine tests that an unknown engine is reported as an error.
+func TestUnknownEngine(t *testing.T) {
+	testIoError(t, gol.Params{Turns: 1, Threads: 1, ImageWidth: 16, ImageHeight: 16, Engine: "quantum"})
+}
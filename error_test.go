@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestIoErrors tests that bad input images are reported as an ErrorOccurred event and that gol.Run returns.
+func TestIoErrors(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		testIoError(t, gol.Params{Turns: 1, Threads: 1, ImageWidth: 17, ImageHeight: 17})
+	})
+
+	t.Run("dimensions", func(t *testing.T) {
+		path := "images/9x9.pgm"
+		if err := os.WriteFile(path, []byte("P5\n8 8\n255\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(path)
+		testIoError(t, gol.Params{Turns: 1, Threads: 1, ImageWidth: 9, ImageHeight: 9})
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		path := "images/9x9.pgm"
+		if err := os.WriteFile(path, []byte("P5\n9 9\n255\n\xff\xff"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(path)
+		testIoError(t, gol.Params{Turns: 1, Threads: 1, ImageWidth: 9, ImageHeight: 9})
+	})
+}
+
+func testIoError(t *testing.T, p gol.Params) {
+	events := make(chan gol.Event)
+	golDone := make(chan bool, 1)
+	go func() {
+		gol.Run(p, events, nil)
+		golDone <- true
+	}()
+
+	var errorEvent *gol.ErrorOccurred
+	quitting := false
+	for event := range events {
+		switch e := event.(type) {
+		case gol.ErrorOccurred:
+			t.Log(e)
+			errorEvent = &e
+		case gol.StateChange:
+			quitting = e.NewState == gol.Quitting
+		case gol.FinalTurnComplete:
+			t.Error("ERROR: FinalTurnComplete should not be sent when the input image can't be read")
+		}
+	}
+
+	assert(t, errorEvent != nil, "No ErrorOccurred event was sent")
+	assert(t, quitting, "The last StateChange event should have a NewState of Quitting")
+	timeout(t, 2*time.Second, func() { <-golDone }, "gol.Run did not return after an io error")
+}
+
+// TestReportErrors tests that a negative report interval or number of report turns is returned by
+// gol.RunContext and sent as an ErrorOccurred event instead of starting the run.
+func TestReportErrors(t *testing.T) {
+	for name, p := range map[string]gol.Params{
+		"interval": {ReportInterval: -time.Second},
+		"turns":    {ReportTurns: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p.Turns, p.Threads, p.ImageWidth, p.ImageHeight = 1, 1, 16, 16
+			events := make(chan gol.Event, 1000)
+			var err error
+			if !timeout(t, 2*time.Second, func() {
+				_, err = gol.RunContext(context.Background(), p, gol.WithEvents(events))
+			}, "gol.RunContext did not return for invalid report params") {
+				return
+			}
+			assert(t, err != nil, "gol.RunContext should return an error for invalid report params")
+
+			errorOccurred := false
+			for event := range events {
+				if e, ok := event.(gol.ErrorOccurred); ok {
+					t.Log(e)
+					errorOccurred = true
+				}
+			}
+			assert(t, errorOccurred, "No ErrorOccurred event was sent")
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package gol
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Policy decides what a Broker does with an event when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// Unbounded never loses events. Events that don't fit in the buffer wait in a queue kept by the
+	// subscriber's own goroutine, so a slow subscriber falls behind but never stalls the engine or the
+	// other subscribers. The queue has no limit, so a subscriber that stops reading holds on to every
+	// event published after it and should only be used by sinks that need all of them, such as a
+	// recording.
+	Unbounded Policy = iota
+	// Drop skips frames when the buffer is full. Cells flipped while the buffer is full are merged
+	// into a single CellsFlipped, in which a cell flipped twice cancels out, and sent along with the
+	// latest TurnComplete, AliveCellsCount and WorldStats as soon as there's room, so that sinks which
+	// draw the world, such as viewers, skip frames but stay correct. Every other event, such as an
+	// ErrorOccurred or StateChange, is queued and always delivered in order. Memory is bounded by the
+	// size of the world for each queued event.
+	Drop
+)
+
+// Subscription is a single subscriber's view of the events published by a Broker.
+type Subscription struct {
+	// Events receives every published event, subject to the policy, and is closed after the last one.
+	Events  <-chan Event
+	policy  Policy
+	out     chan Event
+	queue   chan Event
+	dropped uint64
+	// pending, flips, turn, alive and stats are what a Drop subscription has yet to send because its
+	// buffer was full. pending is sent first, in order, and the rest are merged or replaced as more
+	// events arrive until an event that has to be queued moves them to the end of pending.
+	pending   []Event
+	flips     map[util.Cell]bool
+	flipsTurn int
+	turn      *TurnComplete
+	alive     *AliveCellsCount
+	stats     *WorldStats
+}
+
+// Dropped returns the number of events discarded because the subscriber's buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) publish(event Event) {
+	if s.policy == Unbounded {
+		s.queue <- event
+		return
+	}
+	if s.flush() {
+		select {
+		case s.out <- event:
+			return
+		default:
+		}
+	}
+	s.hold(event)
+}
+
+// flush tries to send the events held back by a Drop subscription, returning whether there was room
+// for all of them.
+func (s *Subscription) flush() bool {
+	for len(s.pending) > 0 {
+		if !s.trySend(s.pending[0]) {
+			return false
+		}
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+	}
+	if len(s.flips) > 0 {
+		if !s.trySend(s.flipped()) {
+			return false
+		}
+		s.flips = nil
+	}
+	if s.turn != nil {
+		if !s.trySend(*s.turn) {
+			return false
+		}
+		s.turn = nil
+	}
+	if s.alive != nil {
+		if !s.trySend(*s.alive) {
+			return false
+		}
+		s.alive = nil
+	}
+	if s.stats != nil {
+		if !s.trySend(*s.stats) {
+			return false
+		}
+		s.stats = nil
+	}
+	return true
+}
+
+// trySend sends event if there's room in the buffer, returning whether it was sent.
+func (s *Subscription) trySend(event Event) bool {
+	select {
+	case s.out <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// hold keeps an event that didn't fit in a Drop subscription's buffer. Flips are merged, and the
+// latest turn and reports replace the ones held before them. A turn held back is discarded once cells
+// flip after it, since the turn that follows them replaces it. Any other event is queued.
+func (s *Subscription) hold(event Event) {
+	var cells []util.Cell
+	switch e := event.(type) {
+	case CellFlipped:
+		cells = []util.Cell{e.Cell}
+	case CellsFlipped:
+		cells = e.Cells
+	case TurnComplete:
+		if s.turn != nil {
+			atomic.AddUint64(&s.dropped, 1)
+		}
+		s.turn = &e
+		return
+	case AliveCellsCount:
+		if s.alive != nil {
+			atomic.AddUint64(&s.dropped, 1)
+		}
+		s.alive = &e
+		return
+	case WorldStats:
+		if s.stats != nil {
+			atomic.AddUint64(&s.dropped, 1)
+		}
+		s.stats = &e
+		return
+	default:
+		s.queueHeld()
+		s.pending = append(s.pending, event)
+		return
+	}
+	if s.turn != nil {
+		s.turn = nil
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	if s.flips == nil {
+		s.flips = make(map[util.Cell]bool)
+	}
+	for _, cell := range cells {
+		if s.flips[cell] {
+			delete(s.flips, cell)
+		} else {
+			s.flips[cell] = true
+		}
+	}
+	s.flipsTurn = event.GetCompletedTurns()
+}
+
+// queueHeld moves the flips, turn and reports held back by a Drop subscription to the end of
+// pending, so that they are sent before any event queued after them.
+func (s *Subscription) queueHeld() {
+	if len(s.flips) > 0 {
+		s.pending = append(s.pending, s.flipped())
+		s.flips = nil
+	}
+	if s.turn != nil {
+		s.pending = append(s.pending, *s.turn)
+		s.turn = nil
+	}
+	if s.alive != nil {
+		s.pending = append(s.pending, *s.alive)
+		s.alive = nil
+	}
+	if s.stats != nil {
+		s.pending = append(s.pending, *s.stats)
+		s.stats = nil
+	}
+}
+
+func (s *Subscription) close() {
+	if s.policy == Unbounded {
+		close(s.queue)
+		return
+	}
+	if s.flush() {
+		close(s.out)
+		return
+	}
+	// The events held back end with what the world ends up as, so wait for room to send them.
+	s.queueHeld()
+	pending := s.pending
+	s.pending = nil
+	go func() {
+		for _, event := range pending {
+			s.out <- event
+		}
+		close(s.out)
+	}()
+}
+
+// flipped returns the flips held back by a Drop subscription as a single event.
+func (s *Subscription) flipped() CellsFlipped {
+	cells := make([]util.Cell, 0, len(s.flips))
+	for cell := range s.flips {
+		cells = append(cells, cell)
+	}
+	return CellsFlipped{s.flipsTurn, cells}
+}
+
+// forward moves events from the queue of an Unbounded subscription to its buffer. It is always ready to
+// receive from the queue, holding on to whatever the subscriber hasn't had room for yet.
+func (s *Subscription) forward() {
+	var pending []Event
+	queue := s.queue
+	for queue != nil || len(pending) > 0 {
+		var out chan<- Event
+		var next Event
+		if len(pending) > 0 {
+			out, next = s.out, pending[0]
+		}
+		select {
+		case event, ok := <-queue:
+			if !ok {
+				queue = nil
+				continue
+			}
+			pending = append(pending, event)
+		case out <- next:
+			pending[0] = nil
+			pending = pending[1:]
+		}
+	}
+	close(s.out)
+}
+
+// Broker fans out a single event stream, such as the events channel passed to Run, to any number
+// of subscribers.
+type Broker struct {
+	mutex         sync.Mutex
+	subscriptions []*Subscription
+	closed        bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{}
+}
+
+// Subscribe registers a new subscriber with its own buffer size and policy.
+// Subscribing after the stream has ended returns an already closed subscription.
+func (b *Broker) Subscribe(buffer int, policy Policy) *Subscription {
+	s := &Subscription{policy: policy, out: make(chan Event, buffer)}
+	s.Events = s.out
+	if policy == Unbounded {
+		s.queue = make(chan Event)
+		go s.forward()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		s.close()
+	} else {
+		b.subscriptions = append(b.subscriptions, s)
+	}
+	return s
+}
+
+// Run publishes every event received from events to all subscribers and closes their channels
+// once events is closed.
+func (b *Broker) Run(events <-chan Event) {
+	for event := range events {
+		b.mutex.Lock()
+		for _, s := range b.subscriptions {
+			s.publish(event)
+		}
+		b.mutex.Unlock()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, s := range b.subscriptions {
+		s.close()
+	}
+	b.closed = true
+}
@@ -0,0 +1,136 @@
+package gol
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// These are the values Params.Compression can take. Each is also the extension appended after
+// ".pgm" to the name of an image stored in that container.
+const (
+	NoCompression = ""
+	Gzip          = "gz"
+	Zlib          = "zlib"
+)
+
+// compressions lists every supported container in the order input images are looked for.
+var compressions = []string{NoCompression, Gzip, Zlib}
+
+// pgmPath returns the path of the image called name inside dir stored with the given compression.
+func pgmPath(dir, name, compression string) string {
+	if compression == NoCompression {
+		return dir + "/" + name + ".pgm"
+	}
+	return dir + "/" + name + ".pgm." + compression
+}
+
+// imageReader reads the decompressed contents of an image file.
+type imageReader struct {
+	*bufio.Reader
+	path         string
+	decompressor io.Closer
+	file         *os.File
+}
+
+// openImage opens the image called name inside dir, trying each supported container in turn.
+func openImage(dir, name string) (*imageReader, error) {
+	for _, compression := range compressions {
+		file, err := os.Open(pgmPath(dir, name, compression))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		r := &imageReader{path: file.Name(), file: file}
+		var source io.Reader = file
+		switch compression {
+		case Gzip:
+			gz, err := gzip.NewReader(bufio.NewReaderSize(file, ioBufferSize))
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("reading %v: %w", file.Name(), err)
+			}
+			source, r.decompressor = gz, gz
+		case Zlib:
+			zr, err := zlib.NewReader(bufio.NewReaderSize(file, ioBufferSize))
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("reading %v: %w", file.Name(), err)
+			}
+			source, r.decompressor = zr, zr
+		}
+		r.Reader = bufio.NewReaderSize(source, ioBufferSize)
+		return r, nil
+	}
+
+	_, err := os.Stat(pgmPath(dir, name, NoCompression))
+	return nil, err
+}
+
+func (r *imageReader) Close() error {
+	if r.decompressor != nil {
+		r.decompressor.Close()
+	}
+	return r.file.Close()
+}
+
+// imageWriter compresses, if required, and buffers the contents of an image file.
+type imageWriter struct {
+	*bufio.Writer
+	path       string
+	compressor io.WriteCloser
+	file       *os.File
+}
+
+// createImage creates the image called name inside dir in the given container, creating any
+// missing directories on the way.
+func createImage(dir, name, compression string) (*imageWriter, error) {
+	if compression != NoCompression && compression != Gzip && compression != Zlib {
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+
+	path := pgmPath(dir, name, compression)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &imageWriter{path: file.Name(), file: file}
+	switch compression {
+	case Gzip:
+		w.compressor = gzip.NewWriter(file)
+	case Zlib:
+		w.compressor = zlib.NewWriter(file)
+	}
+	if w.compressor != nil {
+		w.Writer = bufio.NewWriterSize(w.compressor, ioBufferSize)
+	} else {
+		w.Writer = bufio.NewWriterSize(file, ioBufferSize)
+	}
+	return w, nil
+}
+
+// Close flushes all buffered data through the compressor and syncs the file to disk.
+func (w *imageWriter) Close() error {
+	err := w.Flush()
+	if err == nil && w.compressor != nil {
+		err = w.compressor.Close()
+	}
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
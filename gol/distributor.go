@@ -1,12 +1,11 @@
 package gol
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"net/rpc"
 	"strconv"
 	"time"
-	"uk.ac.bris.cs/gameoflife/stubs"
+
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
@@ -15,87 +14,352 @@ type distributorChannels struct {
 	ioCommand  chan<- ioCommand
 	ioIdle     <-chan bool
 	ioFilename chan<- string
-	ioOutput   chan<- uint8
-	ioInput    <-chan uint8
+	ioSoup     chan<- *Soup
+	ioOutput   chan<- []uint8
+	ioInput    <-chan []uint8
+	ioError    <-chan error
 }
 
 func calculateAliveCells(p Params, world [][]byte) []util.Cell {
 	var aliveCells []util.Cell
-	for j := 0; j < p.ImageWidth; j++ {
-		for i := 0; i < p.ImageHeight; i++ {
-			if world[j][i] == 255 {
-				aliveCells = append(aliveCells, util.Cell{i, j})
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if world[y][x] == 255 {
+				aliveCells = append(aliveCells, util.Cell{X: x, Y: y})
 			}
 		}
 	}
 	return aliveCells
 }
 
-// distributor divides the work between workers and interacts with other goroutines.
-func distributor(p Params, c distributorChannels, keyPresses <-chan rune) {
+// send sends event unless ctx is cancelled first, in which case the event is discarded, so that a
+// run that has been cancelled never waits for events to be read.
+func send(ctx context.Context, c distributorChannels, event Event) {
+	select {
+	case c.events <- event:
+	case <-ctx.Done():
+	}
+}
 
-	// Create a 2D slice to store the world.
+// readWorld requests the input image from the io goroutine and receives it one row at a time.
+func readWorld(ctx context.Context, p Params, c distributorChannels) ([][]byte, error) {
 	world := make([][]byte, p.ImageHeight)
-	for i := range world {
-		world[i] = make([]byte, p.ImageWidth)
-	}
-	//fmt.Println("Sending ioInput command")
-	c.ioCommand <- ioInput
-	var filename = strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight)
-	//fmt.Println("Sending filename to ioFilename channel")
-	c.ioFilename <- filename
-	//fmt.Println("Receiving world data")
+	select {
+	case c.ioCommand <- ioInput:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case c.ioFilename <- strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 	for y := 0; y < p.ImageHeight; y++ {
-		for x := 0; x < p.ImageWidth; x++ {
-			val, ok := <-c.ioInput
-			if !ok {
-				log.Fatal("Error: ioInput channel closed unexpectedly.")
-			}
-			world[y][x] = val
-			if val == 255 {
-				c.events <- CellFlipped{0, util.Cell{x, y}}
+		select {
+		case row := <-c.ioInput:
+			world[y] = row
+		case err := <-c.ioError:
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	select {
+	case err := <-c.ioError:
+		return world, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// writeWorld sends the world to the io goroutine one row at a time and waits for the image to be written.
+// soup is the soup the world came from, which is recorded in the image, or nil if there isn't one.
+func writeWorld(ctx context.Context, p Params, c distributorChannels, world [][]byte, turn int, filename string, soup *Soup) error {
+	select {
+	case c.ioCommand <- ioOutput:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case c.ioFilename <- filename:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case c.ioSoup <- soup:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for y := 0; y < p.ImageHeight; y++ {
+		select {
+		case c.ioOutput <- world[y]:
+		case err := <-c.ioError:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case err := <-c.ioError:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	send(ctx, c, ImageOutputComplete{turn, filename})
+	return nil
+}
+
+// quit reports err, if there is one, and tells the other goroutines that the distributor has finished.
+// Once the context is cancelled nothing more is sent and the channels are only closed, since
+// whoever cancelled the run may have stopped reading events.
+func quit(ctx context.Context, c distributorChannels, turn int, err error) {
+	if err != nil && ctx.Err() == nil {
+		send(ctx, c, ErrorOccurred{turn, err})
+		send(ctx, c, StateChange{CompletedTurns: turn, NewState: Quitting})
+	}
+	close(c.ioCommand)
+	// Close the channel to stop the SDL goroutine gracefully. Removing may cause deadlock.
+	close(c.events)
+}
+
+// report sends the number of alive cells and the stats of the world after turn. Everything but the
+// number of components is kept up to date by tracker as cells flip, and the components are counted
+// with as many goroutines as the engine has threads.
+func report(ctx context.Context, p Params, c distributorChannels, m *runMetrics, tracker *util.StatsTracker, world [][]byte, turn, births, deaths int) {
+	stats := tracker.Stats(world, p.Threads)
+	stats.Births, stats.Deaths = births, deaths
+	m.aliveCells.Set(float64(stats.Alive))
+	send(ctx, c, AliveCellsCount{turn, stats.Alive})
+	send(ctx, c, WorldStats{turn, stats})
+}
+
+// replaceWorld sends the cells that differ between world and next as a CellsFlipped event.
+func replaceWorld(ctx context.Context, c distributorChannels, world, next [][]byte, turn int) {
+	var flipped []util.Cell
+	for y := range world {
+		for x := range world[y] {
+			if world[y][x] != next[y][x] {
+				flipped = append(flipped, util.Cell{X: x, Y: y})
 			}
 		}
 	}
+	if len(flipped) > 0 {
+		send(ctx, c, CellsFlipped{turn, flipped})
+	}
+}
+
+// distributor steps the world with the engine one turn at a time and interacts with other goroutines.
+// It returns the final world, or the last world it knew of and the error that stopped it.
+// It responds to the following key presses between turns:
+//
+//	p   pause and resume
+//	s   output the current world as an image
+//	q   output the current world and quit
+//	k   output the current world, quit and shut down the engine's other components
+//	c   clear the world
+//	r   replace the world with a random soup
+//	n   step one turn while paused, or as many turns as the digits typed before it
+//	[   halve the number of turns per second, limiting it to half the current rate if there is no limit
+//	]   double the number of turns per second, removing the limit past maxTurnsPerSecond
+//
+// Every change of state or rate is sent as a StateChange. Stepping while paused sends a StateChange
+// to Executing before the first turn and back to Paused after the last.
+// Edits are applied to the world as they arrive, so the next turn is computed from the edited world.
+// Metrics are recorded to m.
+func distributor(ctx context.Context, p Params, c distributorChannels, m *runMetrics, keyPresses <-chan rune, edits <-chan Edit) ([][]byte, error) {
+
+	if p.ReportInterval < 0 {
+		err := fmt.Errorf("invalid report interval %v", p.ReportInterval)
+		quit(ctx, c, 0, err)
+		return nil, err
+	}
+	if p.ReportTurns < 0 {
+		err := fmt.Errorf("invalid report turns %v", p.ReportTurns)
+		quit(ctx, c, 0, err)
+		return nil, err
+	}
+	rule, err := util.ParseRule(p.Rule)
+	if err != nil {
+		quit(ctx, c, 0, err)
+		return nil, err
+	}
+	filenames, err := newFilenameTemplate(p, rule, time.Now())
+	if err != nil {
+		quit(ctx, c, 0, err)
+		return nil, err
+	}
+
+	var world [][]byte
+	// soup is the soup the world came from, until it is cleared or edited.
+	soup := p.Soup
+	if p.Soup != nil {
+		world, err = generateSoup(p, *p.Soup)
+	} else {
+		world, err = readWorld(ctx, p, c)
+	}
+	if err != nil {
+		quit(ctx, c, 0, err)
+		return nil, err
+	}
+
+	engine, err := newEngine(ctx, p, m)
+	if err != nil {
+		quit(ctx, c, 0, err)
+		return world, err
+	}
+	defer engine.Close()
+
+	if initialCells := calculateAliveCells(p, world); len(initialCells) > 0 {
+		send(ctx, c, CellsFlipped{0, initialCells})
+	}
 
 	turn := 0
-	c.events <- StateChange{turn, Executing}
-
-	// TODO: Execute all turns of the Game of Life.
-	server := "127.0.0.1:8030"
-	client, _ := rpc.Dial("tcp", server)
-	defer client.Close()
-
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-	done := make(chan bool)
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				request1 := stubs.Request{Grid: world, Height: p.ImageHeight, Width: p.ImageWidth, Turns: p.Turns}
-				response1 := new(stubs.Response)
-				fmt.Println("I have been run")
-				client.Call(stubs.Reporter, request1, response1)
-				c.events <- AliveCellsCount{turn, response1.Alive}
-			case <-done:
-				return
+	pc := newPace(p.TurnsPerSecond)
+	defer pc.stop()
+	send(ctx, c, pc.event(turn))
+
+	// Reports are sent every ReportTurns turns if it is set, or otherwise on every tick.
+	var reportTicks <-chan time.Time
+	if p.ReportTurns == 0 {
+		ticker := time.NewTicker(p.ReportInterval)
+		defer ticker.Stop()
+		reportTicks = ticker.C
+	}
+
+	tracker := util.NewStatsTracker(world, p.ImageWidth, p.ImageHeight, p.StatsRegions)
+	births, deaths := 0, 0
+	rate := newTurnRate(turn, m.turnsPerSecond)
+	// count is the number typed before 'n'.
+	count := 0
+
+	for turn < p.Turns {
+		select {
+		case <-ctx.Done():
+			quit(ctx, c, turn, ctx.Err())
+			return world, ctx.Err()
+
+		case <-reportTicks:
+			rate.update(turn)
+			report(ctx, p, c, m, tracker, world, turn, births, deaths)
+
+		case key := <-keyPresses:
+			typed := count
+			count = 0
+			switch key {
+			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				if typed < maxStepCount {
+					count = typed*10 + int(key-'0')
+				}
+			case 'p':
+				pc.togglePause(turn)
+				send(ctx, c, pc.event(turn))
+			case 'n':
+				if pc.state() == Paused {
+					if typed == 0 {
+						typed = 1
+					}
+					pc.step(typed)
+					send(ctx, c, pc.event(turn))
+				}
+			case '[':
+				pc.slower(turn)
+				send(ctx, c, pc.event(turn))
+			case ']':
+				pc.faster(turn)
+				send(ctx, c, pc.event(turn))
+			case 'c', 'r':
+				replacement := make([][]byte, p.ImageHeight)
+				for y := range replacement {
+					replacement[y] = make([]byte, p.ImageWidth)
+				}
+				soup = nil
+				if key == 'r' {
+					random := Soup{Seed: time.Now().UnixNano()}
+					if p.Soup != nil {
+						random.Density, random.Symmetry = p.Soup.Density, p.Soup.Symmetry
+					}
+					random = random.withDefaults()
+					if replacement, err = generateSoup(p, random); err != nil {
+						quit(ctx, c, turn, err)
+						return world, err
+					}
+					soup = &random
+				}
+				replaceWorld(ctx, c, world, replacement, turn)
+				world = replacement
+				tracker = util.NewStatsTracker(world, p.ImageWidth, p.ImageHeight, p.StatsRegions)
+			case 's', 'q', 'k':
+				if err = writeWorld(ctx, p, c, world, turn, filenames.expand(turn), soup); err != nil {
+					quit(ctx, c, turn, err)
+					return world, err
+				}
+				if key == 's' {
+					continue
+				}
+				if k, ok := engine.(killer); ok && key == 'k' {
+					err = k.Kill()
+				}
+				if err == nil {
+					send(ctx, c, StateChange{turn, Quitting, pc.rate})
+				}
+				quit(ctx, c, turn, err)
+				return world, err
 			}
-		}
-	}()
 
-	request := stubs.Request{Grid: world, Height: p.ImageHeight, Width: p.ImageWidth, Turns: p.Turns}
-	response := new(stubs.Response)
-	client.Call(stubs.ProcessGameOfLife, request, response)
+		case edit := <-edits:
+			x, y := edit.Cell.X, edit.Cell.Y
+			if x < 0 || y < 0 || x >= p.ImageWidth || y >= p.ImageHeight {
+				continue
+			}
+			if alive := world[y][x] == 255; alive != edit.Alive {
+				world[y][x] = ^world[y][x]
+				soup = nil
+				tracker.Flip(edit.Cell, edit.Alive)
+				send(ctx, c, CellsFlipped{turn, []util.Cell{edit.Cell}})
+			}
 
-	fmt.Println("I have been run")
-	world = response.Grid
+		case <-pc.next():
+			nextWorld, flipped, err := engine.Step(ctx, world)
+			if err != nil {
+				quit(ctx, c, turn, err)
+				return world, err
+			}
+			births, deaths = 0, 0
+			for _, cell := range flipped {
+				alive := nextWorld[cell.Y][cell.X] == 255
+				if alive {
+					births++
+				} else {
+					deaths++
+				}
+				tracker.Flip(cell, alive)
+			}
+			world = nextWorld
+			turn++
+			m.turnsCompleted.Inc()
+			if len(flipped) > 0 {
+				send(ctx, c, CellsFlipped{turn, flipped})
+			}
+			send(ctx, c, TurnComplete{turn})
+			if p.ReportTurns > 0 && turn%p.ReportTurns == 0 {
+				rate.update(turn)
+				report(ctx, p, c, m, tracker, world, turn, births, deaths)
+			}
+			if pc.stepped() {
+				send(ctx, c, pc.event(turn))
+			}
+		}
+	}
 
-	c.events <- StateChange{turn, Quitting}
-	c.events <- FinalTurnComplete{CompletedTurns: turn, Alive: calculateAliveCells(p, world)}
-	done <- true
-	// Close the channel to stop the SDL goroutine gracefully. Removing may cause deadlock.
-	close(c.events)
+	if err = writeWorld(ctx, p, c, world, turn, filenames.expand(turn), soup); err != nil {
+		quit(ctx, c, turn, err)
+		return world, err
+	}
 
+	send(ctx, c, StateChange{turn, Quitting, pc.rate})
+	send(ctx, c, FinalTurnComplete{CompletedTurns: turn, Alive: calculateAliveCells(p, world)})
+	quit(ctx, c, turn, nil)
+	return world, nil
 }
@@ -0,0 +1,56 @@
+package gol
+
+import (
+	"context"
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/metrics"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// The engines that Params.Engine can select.
+const (
+	// LocalEngine computes turns in this process, split between Params.Threads goroutines.
+	LocalEngine = "local"
+	// RemoteEngine sends every turn to the servers in Params.Servers over RPC.
+	RemoteEngine = "remote"
+)
+
+// Engine computes turns of the Game of Life. The distributor keeps the world and steps it one turn
+// at a time, so that it can report the cells flipped by every turn and respond to key presses
+// between turns whichever engine is used.
+type Engine interface {
+	// Step returns the world after one turn along with the cells that changed state.
+	// The world passed in is left unmodified.
+	Step(ctx context.Context, world [][]byte) ([][]byte, []util.Cell, error)
+	// Close releases any resources held by the engine.
+	Close() error
+}
+
+// killer is implemented by engines with other components that the 'k' key shuts down.
+type killer interface {
+	Kill() error
+}
+
+// NewEngine creates the engine selected by p.Engine for the world size and rule in p.
+// Connecting to the servers of a remote engine is abandoned if ctx is cancelled.
+// Its metrics are recorded in a registry of its own.
+func NewEngine(ctx context.Context, p Params) (Engine, error) {
+	return newEngine(ctx, p, newRunMetrics(metrics.NewRegistry()))
+}
+
+// newEngine creates the engine selected by p.Engine, which records its metrics to m.
+func newEngine(ctx context.Context, p Params, m *runMetrics) (Engine, error) {
+	p = p.withDefaults()
+	rule, err := util.ParseRule(p.Rule)
+	if err != nil {
+		return nil, err
+	}
+	switch p.Engine {
+	case LocalEngine:
+		return newLocalEngine(p, rule, m), nil
+	case RemoteEngine:
+		return dialRemoteEngine(ctx, p, rule, m)
+	}
+	return nil, fmt.Errorf("unknown engine %q, expected %v or %v", p.Engine, LocalEngine, RemoteEngine)
+}
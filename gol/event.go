@@ -16,7 +16,7 @@ type Event interface {
 }
 
 // `AliveCellsCount` is an Event notifying the user about the number of currently alive cells.
-// This Event should be sent every 2s.
+// This Event should be sent every 2s, or as configured by Params.ReportInterval and Params.ReportTurns.
 type AliveCellsCount struct { // implements Event
 	CompletedTurns int
 	CellsCount     int
@@ -43,6 +43,9 @@ const (
 type StateChange struct { // implements Event
 	CompletedTurns int
 	NewState       State
+	// TurnsPerSecond is the rate turns are throttled to, or 0 if they are computed as fast as possible.
+	// A StateChange is also sent when only the rate changes.
+	TurnsPerSecond float64
 }
 
 // `CellFlipped` is an Event notifying the GUI about a change of state of a single cell.
@@ -78,6 +81,22 @@ type FinalTurnComplete struct {
 	Alive          []util.Cell
 }
 
+// `WorldStats` is an Event summarising the population of the world, kept up to date from the cells
+// flipped each turn rather than measured from scratch.
+// This Event is sent alongside every `AliveCellsCount`.
+type WorldStats struct { // implements Event
+	CompletedTurns int
+	Stats          util.Stats
+}
+
+// `ErrorOccurred` is an Event notifying the user about an error that stopped the execution, such as
+// a missing or malformed input image or an output image that could not be written.
+// It is followed by a StateChange to Quitting, after which the events channel is closed.
+type ErrorOccurred struct { // implements Event
+	CompletedTurns int
+	Err            error
+}
+
 // String methods allow the different types of Events and States to be printed.
 
 func (state State) String() string {
@@ -94,6 +113,9 @@ func (state State) String() string {
 }
 
 func (event StateChange) String() string {
+	if event.TurnsPerSecond > 0 {
+		return fmt.Sprintf("%v at %v turns/s", event.NewState, event.TurnsPerSecond)
+	}
 	return fmt.Sprintf("%v", event.NewState)
 }
 
@@ -149,6 +171,22 @@ func (event FinalTurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event WorldStats) String() string {
+	return fmt.Sprintf("Alive %v Births %v Deaths %v Components %v", event.Stats.Alive, event.Stats.Births, event.Stats.Deaths, event.Stats.Components)
+}
+
+func (event WorldStats) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event ErrorOccurred) String() string {
+	return fmt.Sprintf("Error: %v", event.Err)
+}
+
+func (event ErrorOccurred) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 // This might all seem like weird syntax to you...
 // You have however seen something similar to it before in first year.
 
@@ -0,0 +1,44 @@
+package gol
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// placeholderPattern matches a single {name} placeholder in a filename template.
+var placeholderPattern = regexp.MustCompile(`{[^{}]*}`)
+
+// filenameTemplate names the output images of a run from Params.FilenameTemplate.
+// The supported placeholders are {w}, {h}, {turn}, {rule} and {timestamp}, where {rule} is written
+// without its slash (e.g. B3S23) and {timestamp} is the time the run started.
+type filenameTemplate struct {
+	template string
+	replacer *strings.Replacer
+}
+
+func newFilenameTemplate(p Params, rule util.Rule, start time.Time) (filenameTemplate, error) {
+	for _, placeholder := range placeholderPattern.FindAllString(p.FilenameTemplate, -1) {
+		switch placeholder {
+		case "{w}", "{h}", "{turn}", "{rule}", "{timestamp}":
+		default:
+			return filenameTemplate{}, fmt.Errorf("unknown placeholder %v in filename template %q", placeholder, p.FilenameTemplate)
+		}
+	}
+	replacer := strings.NewReplacer(
+		"{w}", strconv.Itoa(p.ImageWidth),
+		"{h}", strconv.Itoa(p.ImageHeight),
+		"{rule}", strings.ReplaceAll(rule.String(), "/", ""),
+		"{timestamp}", start.Format("20060102T150405.000"),
+	)
+	return filenameTemplate{template: p.FilenameTemplate, replacer: replacer}, nil
+}
+
+// expand returns the name of the image of the given turn, without a directory or extension.
+func (t filenameTemplate) expand(turn int) string {
+	return strings.ReplaceAll(t.replacer.Replace(t.template), "{turn}", strconv.Itoa(turn))
+}
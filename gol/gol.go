@@ -1,40 +1,231 @@
 package gol
 
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/metrics"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Defaults used for the optional fields of Params that are left empty.
+const (
+	DefaultRule             = "B3/S23"
+	DefaultOutDir           = "out"
+	DefaultFilenameTemplate = "{w}x{h}x{turn}"
+	DefaultReportInterval   = 2 * time.Second
+	DefaultEngine           = LocalEngine
+	DefaultServer           = "127.0.0.1:8030"
+	DefaultDialTimeout      = 2 * time.Second
+	DefaultDialRetries      = 3
+)
+
 // Params provides the details of how to run the Game of Life and which image to load.
 type Params struct {
-	Turns       int
+	Turns int
+	// Threads is the number of worker goroutines the local engine splits each turn between.
 	Threads     int
 	ImageWidth  int
 	ImageHeight int
+	// Compression selects the container output images are written in: NoCompression, Gzip or Zlib.
+	// Input images are read from whichever container exists.
+	Compression string
+	// Rule is the rule to evolve the world with in B/S notation. Defaults to DefaultRule.
+	Rule string
+	// OutDir is the directory output images are written to. Defaults to DefaultOutDir.
+	OutDir string
+	// FilenameTemplate names output images using the placeholders {w}, {h}, {turn}, {rule} and {timestamp}.
+	// Defaults to DefaultFilenameTemplate.
+	FilenameTemplate string
+	// ReportInterval is how often AliveCellsCount and WorldStats are sent. Defaults to DefaultReportInterval.
+	// The run fails with an ErrorOccurred if it or ReportTurns is negative.
+	ReportInterval time.Duration
+	// ReportTurns, if set, sends AliveCellsCount and WorldStats after every ReportTurns turns instead
+	// of every ReportInterval, so that exactly one report is sent per ReportTurns completed turns.
+	ReportTurns int
+	// StatsRegions is the number of regions along each side of the world that WorldStats measures
+	// the density over. Defaults to util.DefaultRegions.
+	StatsRegions int
+	// TurnsPerSecond throttles the run to at most this many turns per second, or runs as fast as
+	// possible if it is 0. It can be changed while running with the '[' and ']' keys.
+	TurnsPerSecond float64
+	// Engine selects where turns are computed: LocalEngine or RemoteEngine. Defaults to DefaultEngine.
+	Engine string
+	// Servers are the addresses of the servers used by the remote engine, which splits each turn
+	// between them. Defaults to DefaultServer.
+	Servers []string
+	// DialTimeout limits each attempt to connect to a server. Defaults to DefaultDialTimeout.
+	DialTimeout time.Duration
+	// DialRetries is how many more times connecting to a server is attempted after the first
+	// failure, waiting twice as long before each retry. Zero only attempts once, so
+	// DefaultDialRetries has to be asked for, as the -dial-retries flag does by default.
+	DialRetries int
+	// Soup, if set, generates the initial world randomly instead of reading it from an image.
+	Soup *Soup
+}
+
+// withDefaults returns a copy of p with every empty optional field set to its default.
+func (p Params) withDefaults() Params {
+	if p.Rule == "" {
+		p.Rule = DefaultRule
+	}
+	if p.OutDir == "" {
+		p.OutDir = DefaultOutDir
+	}
+	if p.FilenameTemplate == "" {
+		p.FilenameTemplate = DefaultFilenameTemplate
+	}
+	if p.ReportInterval == 0 {
+		p.ReportInterval = DefaultReportInterval
+	}
+	if p.StatsRegions == 0 {
+		p.StatsRegions = util.DefaultRegions
+	}
+	if p.Engine == "" {
+		p.Engine = DefaultEngine
+	}
+	if len(p.Servers) == 0 {
+		p.Servers = []string{DefaultServer}
+	}
+	if p.DialTimeout == 0 {
+		p.DialTimeout = DefaultDialTimeout
+	}
+	if p.Threads < 1 {
+		p.Threads = 1
+	}
+	if p.Soup != nil {
+		soup := p.Soup.withDefaults()
+		p.Soup = &soup
+	}
+	return p
+}
+
+// Option configures the channels RunContext communicates through.
+type Option func(*runOptions)
+
+type runOptions struct {
+	events     chan<- Event
+	keyPresses <-chan rune
+	edits      <-chan Edit
+	progress   io.Writer
+	metrics    *metrics.Registry
+}
+
+// Edit sets the state of a single cell, such as when the user draws on the world with the mouse.
+type Edit struct {
+	Cell  util.Cell
+	Alive bool
+}
+
+// WithEvents sends every Event to events, which is closed when the run finishes or is cancelled.
+// Without it events are discarded.
+func WithEvents(events chan<- Event) Option {
+	return func(o *runOptions) {
+		o.events = events
+	}
+}
+
+// WithKeyPresses controls the run with the same key presses as the SDL window.
+func WithKeyPresses(keyPresses <-chan rune) Option {
+	return func(o *runOptions) {
+		o.keyPresses = keyPresses
+	}
+}
+
+// WithEdits applies each Edit to the world before the next turn is computed.
+// The cells that change are sent as a CellsFlipped event.
+func WithEdits(edits <-chan Edit) Option {
+	return func(o *runOptions) {
+		o.edits = edits
+	}
+}
+
+// WithProgress writes the lines reporting that an image has been read or written to progress
+// instead of stdout, such as io.Discard while stdout is taken over by a viewer. Every image written
+// is still sent as an ImageOutputComplete event.
+func WithProgress(progress io.Writer) Option {
+	return func(o *runOptions) {
+		o.progress = progress
+	}
+}
+
+// WithMetrics records the metrics of the run, such as gol_turns_completed_total, to registry, which
+// may be served over HTTP. Without it every run records to a registry of its own, which isn't
+// served, so that concurrent runs can't overwrite each other's metrics.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(o *runOptions) {
+		o.metrics = registry
+	}
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	_, _ = RunContext(context.Background(), p, WithEvents(events), WithKeyPresses(keyPresses))
+}
+
+// RunContext runs the Game of Life until every turn is complete or ctx is cancelled, then returns the
+// final world. If the run is stopped by an error or cancellation it returns the last world it had
+// along with the error. The events channel is closed after a StateChange to Quitting, or as soon
+// as possible once ctx is cancelled, when events stop being sent and the channel is only closed,
+// so that cancelling a run and no longer reading its events never leaves it blocked.
+// Every goroutine started by the run has finished or is finishing when it returns.
+func RunContext(ctx context.Context, p Params, opts ...Option) ([][]byte, error) {
+	p = p.withDefaults()
+
+	var o runOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	events := o.events
+	if events == nil {
+		discard := make(chan Event, 1000)
+		go func() {
+			for range discard {
+			}
+		}()
+		events = discard
+	}
+	progress := o.progress
+	if progress == nil {
+		progress = os.Stdout
+	}
+	registry := o.metrics
+	if registry == nil {
+		registry = metrics.NewRegistry()
+	}
 
 	//	TODO: Put the missing channels in here.
 
 	ioCommand := make(chan ioCommand)
 	ioIdle := make(chan bool)
 	ioFilename := make(chan string)
-	ioOutput := make(chan uint8)
-	ioInput := make(chan uint8)
+	ioSoup := make(chan *Soup)
+	ioOutput := make(chan []uint8)
+	ioInput := make(chan []uint8)
+	ioError := make(chan error)
 
 	ioChannels := ioChannels{
 		command:  ioCommand,
 		idle:     ioIdle,
 		filename: ioFilename,
+		soup:     ioSoup,
 		output:   ioOutput,
 		input:    ioInput,
+		err:      ioError,
 	}
-	go startIo(p, ioChannels)
+	go startIo(ctx, p, ioChannels, progress)
 
 	distributorChannels := distributorChannels{
 		events:     events,
 		ioCommand:  ioCommand,
 		ioIdle:     ioIdle,
 		ioFilename: ioFilename,
+		ioSoup:     ioSoup,
 		ioOutput:   ioOutput,
 		ioInput:    ioInput,
+		ioError:    ioError,
 	}
-	distributor(p, distributorChannels, keyPresses)
+	return distributor(ctx, p, distributorChannels, newRunMetrics(registry), o.keyPresses, o.edits)
 }
@@ -1,11 +1,12 @@
 package gol
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
-	"strings"
-	"uk.ac.bris.cs/gameoflife/util"
 )
 
 type ioChannels struct {
@@ -13,19 +14,28 @@ type ioChannels struct {
 	idle    chan<- bool
 
 	filename <-chan string
-	output   <-chan uint8
-	input    chan<- uint8
+	soup     <-chan *Soup
+	output   <-chan []uint8
+	input    chan<- []uint8
+	err      chan<- error
 }
 
 // ioState is the internal ioState of the io goroutine.
 type ioState struct {
 	params   Params
 	channels ioChannels
+	// ctx is the context of the run, which stops the io goroutine waiting on the distributor.
+	ctx context.Context
+	// progress is where a line is written after each image is read or written.
+	progress io.Writer
 }
 
 // ioCommand allows requesting behaviour from the io (pgm) goroutine.
 type ioCommand uint8
 
+// Every ioInput and ioOutput command is answered with exactly one value on the err channel once it
+// has finished or failed: nil on success, otherwise the error that stopped it.
+//
 // This is a way of creating enums in Go.
 // It will evaluate to:
 //		ioOutput 	= 0
@@ -37,19 +47,41 @@ const (
 	ioCheckIdle
 )
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
-func (io *ioState) writePgmImage() {
-	_ = os.Mkdir("out", os.ModePerm)
+// ioBufferSize is the size of the buffered readers and writers wrapped around pgm files.
+const ioBufferSize = 1 << 16
 
+// writePgmImage receives the world one row at a time and writes it to a pgm file, compressed as
+// selected by Params.Compression.
+// Each row is written out before the next one is received, so the distributor must not modify
+// the rows it has sent until it has received the result of the command.
+// If an error is returned the distributor should stop sending rows.
+func (io *ioState) writePgmImage() error {
 	// Request a filename from the distributor.
-	filename := <-io.channels.filename
+	var filename string
+	select {
+	case filename = <-io.channels.filename:
+	case <-io.ctx.Done():
+		return io.ctx.Err()
+	}
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	util.Check(ioError)
-	defer file.Close()
+	// The distributor sends the soup the world came from, or nil once it has been cleared or edited.
+	var soup *Soup
+	select {
+	case soup = <-io.channels.soup:
+	case <-io.ctx.Done():
+		return io.ctx.Err()
+	}
+
+	file, err := createImage(io.params.OutDir, filename, io.params.Compression)
+	if err != nil {
+		return err
+	}
 
 	_, _ = file.WriteString("P5\n")
 	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
+	if soup != nil {
+		_, _ = file.WriteString("# " + soup.String() + "\n")
+	}
 	_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
 	_, _ = file.WriteString(" ")
 	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
@@ -57,89 +89,196 @@ func (io *ioState) writePgmImage() {
 	_, _ = file.WriteString(strconv.Itoa(255))
 	_, _ = file.WriteString("\n")
 
-	world := make([][]byte, io.params.ImageHeight)
-	for i := range world {
-		world[i] = make([]byte, io.params.ImageWidth)
-	}
-
 	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			val := <-io.channels.output
-			//if val != 0 {
-			//	fmt.Println(x, y)
-			//}
-			world[y][x] = val
+		var row []uint8
+		select {
+		case row = <-io.channels.output:
+		case <-io.ctx.Done():
+			_ = file.Close()
+			return io.ctx.Err()
 		}
-	}
-
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			util.Check(ioError)
+		if _, err = file.Write(row); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("writing %v: %w", file.path, err)
 		}
 	}
 
-	ioError = file.Sync()
-	util.Check(ioError)
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("writing %v: %w", file.path, err)
+	}
 
-	fmt.Println("File", filename, "output done!")
+	fmt.Fprintln(io.progress, "File", filename, "output done!")
+	return nil
 }
 
-// readPgmImage opens a pgm file and sends its data as an array of bytes.
-func (io *ioState) readPgmImage() {
+// readPgmImage opens a pgm file, decompressing it if it is stored in a compressed container,
+// and sends its data one row at a time.
+// Every row is a freshly allocated slice that the distributor may keep as part of its world.
+// If an error is returned no further rows will be sent.
+func (io *ioState) readPgmImage() error {
 
 	// Request a filename from the distributor.
-	filename := <-io.channels.filename
-
-	data, ioError := os.ReadFile("images/" + filename + ".pgm")
-	util.Check(ioError)
+	var filename string
+	select {
+	case filename = <-io.channels.filename:
+	case <-io.ctx.Done():
+		return io.ctx.Err()
+	}
 
-	fields := strings.Fields(string(data))
+	file, err := openImage("images", filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+	width, height, err := readPgmHeader(file.Reader, file.path)
+	if err != nil {
+		return err
 	}
 
-	width, _ := strconv.Atoi(fields[1])
 	if width != io.params.ImageWidth {
-		panic("Incorrect width")
+		return fmt.Errorf("reading %v: incorrect width %v, expected %v", file.path, width, io.params.ImageWidth)
 	}
 
-	height, _ := strconv.Atoi(fields[2])
 	if height != io.params.ImageHeight {
-		panic("Incorrect height")
+		return fmt.Errorf("reading %v: incorrect height %v, expected %v", file.path, height, io.params.ImageHeight)
 	}
 
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+	for y := 0; y < io.params.ImageHeight; y++ {
+		row := make([]uint8, io.params.ImageWidth)
+		if err = readPgmRow(file.Reader, row); err != nil {
+			return fmt.Errorf("reading %v: row %v: %w", file.path, y, err)
+		}
+		select {
+		case io.channels.input <- row:
+		case <-io.ctx.Done():
+			return io.ctx.Err()
+		}
 	}
 
-	image := []byte(fields[4])
+	fmt.Fprintln(io.progress, "File", filename, "input done!")
+	return nil
+}
 
-	for _, b := range image {
-		io.channels.input <- b
+// ReadPgm reads the binary pgm image at path, such as images/16x16.pgm, and returns its rows.
+// Its header is read in the same way as the images a run starts from, comments included.
+func ReadPgm(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, ioBufferSize)
 
-	fmt.Println("File", filename, "input done!")
+	width, height, err := readPgmHeader(reader, path)
+	if err != nil {
+		return nil, err
+	}
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+		if err = readPgmRow(reader, world[y]); err != nil {
+			return nil, fmt.Errorf("reading %v: row %v: %w", path, y, err)
+		}
+	}
+	return world, nil
+}
+
+// readPgmHeader reads the header of a binary pgm file, leaving reader at the first pixel, and
+// returns the width and height of the image. Only images with a maxval of 255 are accepted.
+func readPgmHeader(reader *bufio.Reader, path string) (width, height int, err error) {
+	var header [4]int
+	for i := range header {
+		field, err := readPgmField(reader)
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading %v: %w", path, err)
+		}
+		if i == 0 {
+			if field != "P5" {
+				return 0, 0, fmt.Errorf("reading %v: not a pgm file", path)
+			}
+			continue
+		}
+		header[i], err = strconv.Atoi(field)
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading %v: malformed header field %q", path, field)
+		}
+	}
+
+	if header[1] <= 0 || header[2] <= 0 {
+		return 0, 0, fmt.Errorf("reading %v: invalid size %vx%v", path, header[1], header[2])
+	}
+
+	if maxval := header[3]; maxval != 255 {
+		return 0, 0, fmt.Errorf("reading %v: incorrect maxval/bit depth %v", path, maxval)
+	}
+	return header[1], header[2], nil
+}
+
+// readPgmField reads a single whitespace-separated header field from a pgm file, skipping comments.
+// The single whitespace character that terminates the field is consumed, so after the maxval
+// field the reader is positioned at the first byte of the image.
+func readPgmField(reader *bufio.Reader) (string, error) {
+	var field []byte
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF && len(field) > 0 {
+			return string(field), nil
+		} else if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		} else if err != nil {
+			return "", err
+		}
+		if b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			if len(field) > 0 {
+				return string(field), nil
+			}
+			continue
+		}
+		if b == '#' && len(field) == 0 {
+			if _, err = reader.ReadString('\n'); err != nil {
+				return "", err
+			}
+			continue
+		}
+		field = append(field, b)
+	}
+}
+
+// readPgmRow fills row with the next len(row) pixels of the image.
+func readPgmRow(reader *bufio.Reader, row []uint8) error {
+	_, err := io.ReadFull(reader, row)
+	return err
 }
 
 // startIo should be the entrypoint of the io goroutine.
-func startIo(p Params, c ioChannels) {
+// It finishes once the command channel is closed, and stops waiting on the distributor once ctx is cancelled.
+func startIo(ctx context.Context, p Params, c ioChannels, progress io.Writer) {
 	io := ioState{
 		params:   p,
 		channels: c,
+		ctx:      ctx,
+		progress: progress,
 	}
 
 	for command := range io.channels.command {
 		// Block and wait for requests from the distributor
+		var err error
 		switch command {
 		case ioInput:
-			io.readPgmImage()
+			err = io.readPgmImage()
 		case ioOutput:
-			io.writePgmImage()
+			err = io.writePgmImage()
 		case ioCheckIdle:
-			io.channels.idle <- true
+			select {
+			case io.channels.idle <- true:
+			case <-ctx.Done():
+			}
+			continue
+		}
+		select {
+		case io.channels.err <- err:
+		case <-ctx.Done():
 		}
 	}
 }
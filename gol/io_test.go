@@ -0,0 +1,125 @@
+package gol
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+var ioBenchmarkSizes = []int{512, 5120}
+
+// setupIoBenchmark moves into a fresh directory containing a random images/<size>x<size>.pgm
+// and returns the params and channels of an io goroutine started in it.
+func setupIoBenchmark(b *testing.B, size int) (Params, distributorChannels) {
+	dir := b.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = os.Chdir(cwd) })
+
+	if err := os.Mkdir("images", os.ModePerm); err != nil {
+		b.Fatal(err)
+	}
+	header := "P5\n" + strconv.Itoa(size) + " " + strconv.Itoa(size) + "\n255\n"
+	data := make([]byte, size*size)
+	for i := range data {
+		if (i*7919)%5 == 0 {
+			data[i] = 255
+		}
+	}
+	path := filepath.Join("images", fmt.Sprintf("%vx%v.pgm", size, size))
+	if err := os.WriteFile(path, append([]byte(header), data...), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	p := Params{ImageWidth: size, ImageHeight: size}.withDefaults()
+	command := make(chan ioCommand)
+	idle := make(chan bool)
+	filename := make(chan string)
+	soup := make(chan *Soup)
+	output := make(chan []uint8)
+	input := make(chan []uint8)
+	ioError := make(chan error)
+	go startIo(context.Background(), p, ioChannels{command: command, idle: idle, filename: filename, soup: soup, output: output, input: input, err: ioError}, io.Discard)
+	b.Cleanup(func() { close(command) })
+
+	return p, distributorChannels{ioCommand: command, ioIdle: idle, ioFilename: filename, ioSoup: soup, ioOutput: output, ioInput: input, ioError: ioError}
+}
+
+// BenchmarkReadPgmImage measures loading a world through the row based io protocol.
+func BenchmarkReadPgmImage(b *testing.B) {
+	for _, size := range ioBenchmarkSizes {
+		b.Run(fmt.Sprintf("%vx%v", size, size), func(b *testing.B) {
+			p, c := setupIoBenchmark(b, size)
+			b.SetBytes(int64(size * size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := readWorld(context.Background(), p, c); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWritePgmImage measures saving a world through the row based io protocol.
+func BenchmarkWritePgmImage(b *testing.B) {
+	for _, size := range ioBenchmarkSizes {
+		b.Run(fmt.Sprintf("%vx%v", size, size), func(b *testing.B) {
+			p, c := setupIoBenchmark(b, size)
+			events := make(chan Event, 1)
+			c.events = events
+			world := make([][]byte, p.ImageHeight)
+			for y := range world {
+				world[y] = make([]byte, p.ImageWidth)
+			}
+			b.SetBytes(int64(size * size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := writeWorld(context.Background(), p, c, world, 0, "bench", nil); err != nil {
+					b.Fatal(err)
+				}
+				<-events
+			}
+		})
+	}
+}
+
+// BenchmarkPerPixelTransfer is the baseline for the benchmarks above: it reproduces the old
+// protocol of one unbuffered channel send per pixel and one file.Write per byte.
+// Only the smallest size is run as the larger ones take tens of seconds per iteration.
+func BenchmarkPerPixelTransfer(b *testing.B) {
+	for _, size := range ioBenchmarkSizes[:1] {
+		b.Run(fmt.Sprintf("%vx%v", size, size), func(b *testing.B) {
+			file, err := os.Create(filepath.Join(b.TempDir(), "bench.pgm"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer file.Close()
+			pixels := make(chan uint8)
+			b.SetBytes(int64(size * size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				go func() {
+					for j := 0; j < size*size; j++ {
+						pixels <- 0
+					}
+				}()
+				for j := 0; j < size*size; j++ {
+					_, err = file.Write([]byte{<-pixels})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
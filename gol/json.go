@@ -0,0 +1,150 @@
+package gol
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// eventTypes maps the name each Event is tagged with in JSON to its type.
+// Every type of Event must be listed here to be encoded.
+var eventTypes = registerEvents(
+	AliveCellsCount{},
+	ImageOutputComplete{},
+	StateChange{},
+	CellFlipped{},
+	CellsFlipped{},
+	TurnComplete{},
+	FinalTurnComplete{},
+	ErrorOccurred{},
+	WorldStats{},
+)
+
+func registerEvents(events ...Event) map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	for _, event := range events {
+		t := reflect.TypeOf(event)
+		types[t.Name()] = t
+	}
+	return types
+}
+
+// MarshalEvent encodes an Event as a JSON object of its fields tagged with the name of its type, e.g.
+// {"Type":"AliveCellsCount","CompletedTurns":2,"CellsCount":5}
+func MarshalEvent(event Event) ([]byte, error) {
+	name := reflect.TypeOf(event).Name()
+	if _, ok := eventTypes[name]; !ok {
+		return nil, fmt.Errorf("cannot encode unknown event type %T", event)
+	}
+	fields, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	tag := `{"Type":"` + name + `"`
+	if len(fields) > 2 {
+		tag += ","
+	}
+	return append([]byte(tag), fields[1:]...), nil
+}
+
+// UnmarshalEvent decodes an Event encoded by MarshalEvent.
+func UnmarshalEvent(data []byte) (Event, error) {
+	var tagged struct{ Type string }
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	}
+	t, ok := eventTypes[tagged.Type]
+	if !ok {
+		return nil, fmt.Errorf("cannot decode unknown event type %q", tagged.Type)
+	}
+	event := reflect.New(t)
+	if err := json.Unmarshal(data, event.Interface()); err != nil {
+		return nil, err
+	}
+	return event.Elem().Interface().(Event), nil
+}
+
+// EventEncoder writes Events to a stream in JSON Lines format, one MarshalEvent object per line.
+type EventEncoder struct {
+	writer *bufio.Writer
+}
+
+func NewEventEncoder(w io.Writer) *EventEncoder {
+	return &EventEncoder{writer: bufio.NewWriter(w)}
+}
+
+// Encode writes a single Event. Events are buffered until Flush is called.
+func (e *EventEncoder) Encode(event Event) error {
+	data, err := MarshalEvent(event)
+	if err != nil {
+		return err
+	}
+	if _, err = e.writer.Write(data); err != nil {
+		return err
+	}
+	return e.writer.WriteByte('\n')
+}
+
+func (e *EventEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+// EventDecoder reads Events from a stream written by an EventEncoder.
+type EventDecoder struct {
+	decoder *json.Decoder
+}
+
+func NewEventDecoder(r io.Reader) *EventDecoder {
+	return &EventDecoder{decoder: json.NewDecoder(r)}
+}
+
+// Decode reads the next Event. It returns io.EOF at the end of the stream.
+func (d *EventDecoder) Decode() (Event, error) {
+	var data json.RawMessage
+	if err := d.decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return UnmarshalEvent(data)
+}
+
+// MarshalText encodes a State by its name, so that it is readable in JSON.
+func (state State) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+func (state *State) UnmarshalText(text []byte) error {
+	for _, s := range []State{Paused, Executing, Quitting} {
+		if s.String() == string(text) {
+			*state = s
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown state %q", text)
+}
+
+// errorOccurredJSON is the JSON form of ErrorOccurred, which stores the error as its message.
+type errorOccurredJSON struct {
+	CompletedTurns int
+	Err            string
+}
+
+func (event ErrorOccurred) MarshalJSON() ([]byte, error) {
+	message := ""
+	if event.Err != nil {
+		message = event.Err.Error()
+	}
+	return json.Marshal(errorOccurredJSON{event.CompletedTurns, message})
+}
+
+func (event *ErrorOccurred) UnmarshalJSON(data []byte) error {
+	var decoded errorOccurredJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	event.CompletedTurns = decoded.CompletedTurns
+	event.Err = errors.New(decoded.Err)
+	return nil
+}
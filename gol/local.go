@@ -0,0 +1,73 @@
+package gol
+
+import (
+	"context"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// localEngine computes each turn in this process, giving every worker goroutine an equal strip of rows.
+type localEngine struct {
+	width, height int
+	threads       int
+	rule          util.Rule
+	metrics       *runMetrics
+}
+
+func newLocalEngine(p Params, rule util.Rule, m *runMetrics) *localEngine {
+	return &localEngine{width: p.ImageWidth, height: p.ImageHeight, threads: p.Threads, rule: rule, metrics: m}
+}
+
+func (e *localEngine) Step(ctx context.Context, world [][]byte) ([][]byte, []util.Cell, error) {
+	if err := ctx.Err(); err != nil {
+		return world, nil, err
+	}
+	next := make([][]byte, e.height)
+	results := make([]chan []util.Cell, e.threads)
+	for i := range results {
+		results[i] = make(chan []util.Cell, 1)
+		startY, endY := i*e.height/e.threads, (i+1)*e.height/e.threads
+		go func(worker int, out chan<- []util.Cell) {
+			defer e.metrics.observeStep(worker, time.Now())
+			out <- stepRows(world, next, e.width, e.height, startY, endY, e.rule)
+		}(i, results[i])
+	}
+	var flipped []util.Cell
+	for _, result := range results {
+		flipped = append(flipped, <-result...)
+	}
+	return next, flipped, nil
+}
+
+func (e *localEngine) Close() error {
+	return nil
+}
+
+// stepRows fills in rows startY to endY of next from world, wrapping around the edges,
+// and returns the cells in those rows that changed state.
+func stepRows(world, next [][]byte, width, height, startY, endY int, rule util.Rule) []util.Cell {
+	var flipped []util.Cell
+	for y := startY; y < endY; y++ {
+		above, row, below := world[(y+height-1)%height], world[y], world[(y+1)%height]
+		next[y] = make([]byte, width)
+		for x := 0; x < width; x++ {
+			left, right := (x+width-1)%width, (x+1)%width
+			neighbours := 0
+			for _, cell := range [8]byte{above[left], above[x], above[right], row[left], row[right], below[left], below[x], below[right]} {
+				if cell == 255 {
+					neighbours++
+				}
+			}
+			alive := row[x] == 255
+			nextAlive := rule.Next(alive, neighbours)
+			if nextAlive {
+				next[y][x] = 255
+			}
+			if nextAlive != alive {
+				flipped = append(flipped, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return flipped
+}
@@ -0,0 +1,70 @@
+package gol
+
+import (
+	"strconv"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/metrics"
+)
+
+// runMetrics are the metrics recorded by a run, in the registry given to WithMetrics or otherwise
+// in one of the run's own, so that concurrent runs don't overwrite each other's gauges.
+type runMetrics struct {
+	turnsCompleted    *metrics.Counter
+	turnsPerSecond    *metrics.Gauge
+	aliveCells        *metrics.Gauge
+	workerStepSeconds *metrics.Histogram
+	rpcSeconds        *metrics.Histogram
+	rpcBytes          *metrics.Counter
+}
+
+func newRunMetrics(r *metrics.Registry) *runMetrics {
+	return &runMetrics{
+		turnsCompleted: r.NewCounter(
+			"gol_turns_completed_total",
+			"Turns completed by the distributor."),
+		turnsPerSecond: r.NewGauge(
+			"gol_turns_per_second",
+			"Turns completed per second between the last two reports."),
+		aliveCells: r.NewGauge(
+			"gol_alive_cells",
+			"Alive cells at the last report."),
+		workerStepSeconds: r.NewHistogram(
+			"gol_worker_step_seconds",
+			"Time taken by each worker of the local engine to compute its strip of a turn.",
+			metrics.DefaultBuckets, "worker"),
+		rpcSeconds: r.NewHistogram(
+			"gol_rpc_duration_seconds",
+			"Latency of RPC calls to servers.",
+			metrics.DefaultBuckets, "server", "method"),
+		rpcBytes: r.NewCounter(
+			"gol_rpc_bytes_total",
+			"Bytes sent to and received from servers.",
+			"server", "direction"),
+	}
+}
+
+// turnRate measures the number of turns per second between reports.
+type turnRate struct {
+	turn  int
+	time  time.Time
+	gauge *metrics.Gauge
+}
+
+func newTurnRate(turn int, gauge *metrics.Gauge) *turnRate {
+	return &turnRate{turn, time.Now(), gauge}
+}
+
+// update sets gol_turns_per_second to the rate since the last update.
+func (r *turnRate) update(turn int) {
+	now := time.Now()
+	if elapsed := now.Sub(r.time).Seconds(); elapsed > 0 {
+		r.gauge.Set(float64(turn-r.turn) / elapsed)
+	}
+	r.turn, r.time = turn, now
+}
+
+// observeStep records how long worker took to compute its strip since start.
+func (m *runMetrics) observeStep(worker int, start time.Time) {
+	m.workerStepSeconds.Observe(time.Since(start).Seconds(), strconv.Itoa(worker))
+}
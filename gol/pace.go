@@ -0,0 +1,125 @@
+package gol
+
+import (
+	"time"
+)
+
+// The rate turns can be throttled to is limited to between minTurnsPerSecond and maxTurnsPerSecond.
+// Speeding up past maxTurnsPerSecond removes the limit.
+const (
+	minTurnsPerSecond = 1.0 / 16
+	maxTurnsPerSecond = 10000
+)
+
+// maxStepCount limits the number of turns that can be typed before 'n'.
+const maxStepCount = 100000000
+
+// pace decides when the distributor steps the next turn: as soon as possible, at a limited number
+// of turns per second, or only a given number of turns at a time while paused.
+type pace struct {
+	paused bool
+	// steps is the number of turns left to step while paused.
+	steps int
+	// rate is the number of turns per second stepped, or 0 for as many as possible.
+	rate   float64
+	ticker *time.Ticker
+	ready  chan time.Time
+	// The rate is measured from measureTurn at measureTime, to be able to slow down from no limit.
+	measureTurn int
+	measureTime time.Time
+}
+
+func newPace(rate float64) *pace {
+	ready := make(chan time.Time)
+	close(ready)
+	pc := &pace{ready: ready}
+	pc.setRate(rate, 0)
+	return pc
+}
+
+// next returns a channel that can be received from when the next turn should be stepped, or nil
+// while paused.
+func (pc *pace) next() <-chan time.Time {
+	switch {
+	case pc.state() == Paused:
+		return nil
+	case pc.ticker != nil:
+		return pc.ticker.C
+	default:
+		return pc.ready
+	}
+}
+
+func (pc *pace) state() State {
+	if pc.paused && pc.steps == 0 {
+		return Paused
+	}
+	return Executing
+}
+
+// event returns the StateChange describing the current state and rate.
+func (pc *pace) event(turn int) StateChange {
+	return StateChange{CompletedTurns: turn, NewState: pc.state(), TurnsPerSecond: pc.rate}
+}
+
+func (pc *pace) togglePause(turn int) {
+	pc.paused = !pc.paused
+	pc.steps = 0
+	pc.measureTurn, pc.measureTime = turn, time.Now()
+}
+
+// step steps n turns while paused.
+func (pc *pace) step(n int) {
+	if pc.paused {
+		pc.steps = n
+	}
+}
+
+// stepped records that a turn was stepped and returns true if it was the last of a step while paused.
+func (pc *pace) stepped() bool {
+	if pc.steps == 0 {
+		return false
+	}
+	pc.steps--
+	return pc.steps == 0
+}
+
+func (pc *pace) setRate(rate float64, turn int) {
+	if rate > maxTurnsPerSecond {
+		rate = 0
+	} else if rate > 0 && rate < minTurnsPerSecond {
+		rate = minTurnsPerSecond
+	}
+	pc.stop()
+	pc.rate = rate
+	if rate > 0 {
+		pc.ticker = time.NewTicker(time.Duration(float64(time.Second) / rate))
+	}
+	pc.measureTurn, pc.measureTime = turn, time.Now()
+}
+
+// slower halves the rate. Without a limit it halves the rate measured since the last change instead.
+func (pc *pace) slower(turn int) {
+	rate := pc.rate
+	if rate == 0 {
+		rate = maxTurnsPerSecond
+		if seconds := time.Since(pc.measureTime).Seconds(); seconds > 0 && turn > pc.measureTurn {
+			rate = float64(turn-pc.measureTurn) / seconds
+		}
+	}
+	pc.setRate(rate/2, turn)
+}
+
+// faster doubles the rate, if it is limited.
+func (pc *pace) faster(turn int) {
+	if pc.rate > 0 {
+		pc.setRate(pc.rate*2, turn)
+	}
+}
+
+func (pc *pace) stop() {
+	if pc.ticker != nil {
+		pc.ticker.Stop()
+		pc.ticker = nil
+	}
+}
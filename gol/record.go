@@ -0,0 +1,336 @@
+package gol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// recordingMagic starts every recording file, followed by recordingVersion.
+// Version 2 added the rate to StateChange events. Version 1 recordings can still be read.
+const (
+	recordingMagic   = "GOLR"
+	recordingVersion = 2
+)
+
+// These tags identify the type of each event in a recording.
+// Events without a compact encoding of their own are stored as tagJSON followed by MarshalEvent.
+const (
+	tagAliveCellsCount byte = iota + 1
+	tagImageOutputComplete
+	tagStateChange
+	tagCellFlipped
+	tagCellsFlipped
+	tagTurnComplete
+	tagFinalTurnComplete
+	tagErrorOccurred
+	tagJSON byte = 0xFF
+)
+
+// Recorder writes an event stream to a compact binary recording.
+// Integers are stored as varints and cells as the difference between consecutive cell indices,
+// so a turn costs a few bytes per flipped cell.
+type Recorder struct {
+	writer *bufio.Writer
+	width  int
+	buffer []byte
+}
+
+// NewRecorder writes the header of a recording of a world of the given size.
+func NewRecorder(w io.Writer, width, height int) (*Recorder, error) {
+	r := &Recorder{writer: bufio.NewWriter(w), width: width}
+	r.buffer = append(r.buffer, recordingMagic...)
+	r.buffer = append(r.buffer, recordingVersion)
+	r.buffer = appendUvarint(r.buffer, uint64(width))
+	r.buffer = appendUvarint(r.buffer, uint64(height))
+	return r, r.flushBuffer()
+}
+
+// Record appends a single event to the recording. Events are buffered until Flush is called.
+func (r *Recorder) Record(event Event) error {
+	b := r.buffer[:0]
+	switch e := event.(type) {
+	case AliveCellsCount:
+		b = appendHeader(b, tagAliveCellsCount, e.CompletedTurns)
+		b = appendUvarint(b, uint64(e.CellsCount))
+	case ImageOutputComplete:
+		b = appendHeader(b, tagImageOutputComplete, e.CompletedTurns)
+		b = appendString(b, e.Filename)
+	case StateChange:
+		b = appendHeader(b, tagStateChange, e.CompletedTurns)
+		b = append(b, byte(e.NewState))
+		b = appendFloat(b, e.TurnsPerSecond)
+	case CellFlipped:
+		b = appendHeader(b, tagCellFlipped, e.CompletedTurns)
+		b = appendUvarint(b, uint64(e.Cell.Y*r.width+e.Cell.X))
+	case CellsFlipped:
+		b = appendHeader(b, tagCellsFlipped, e.CompletedTurns)
+		b = r.appendCells(b, e.Cells)
+	case TurnComplete:
+		b = appendHeader(b, tagTurnComplete, e.CompletedTurns)
+	case FinalTurnComplete:
+		b = appendHeader(b, tagFinalTurnComplete, e.CompletedTurns)
+		b = r.appendCells(b, e.Alive)
+	case ErrorOccurred:
+		b = appendHeader(b, tagErrorOccurred, e.CompletedTurns)
+		message := ""
+		if e.Err != nil {
+			message = e.Err.Error()
+		}
+		b = appendString(b, message)
+	default:
+		data, err := MarshalEvent(event)
+		if err != nil {
+			return err
+		}
+		b = append(b, tagJSON)
+		b = appendUvarint(b, uint64(len(data)))
+		b = append(b, data...)
+	}
+	r.buffer = b
+	return r.flushBuffer()
+}
+
+func (r *Recorder) Flush() error {
+	return r.writer.Flush()
+}
+
+func (r *Recorder) flushBuffer() error {
+	_, err := r.writer.Write(r.buffer)
+	return err
+}
+
+func (r *Recorder) appendCells(b []byte, cells []util.Cell) []byte {
+	b = appendUvarint(b, uint64(len(cells)))
+	previous := 0
+	for _, cell := range cells {
+		index := cell.Y*r.width + cell.X
+		b = appendVarint(b, int64(index-previous))
+		previous = index
+	}
+	return b
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+	return append(b, buf[:]...)
+}
+
+func appendHeader(b []byte, tag byte, completedTurns int) []byte {
+	return appendUvarint(append(b, tag), uint64(completedTurns))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUvarint(b, uint64(len(s))), s...)
+}
+
+// Recording is an event stream read back from a file written by a Recorder.
+type Recording struct {
+	Width, Height int
+	Events        []Event
+}
+
+// ReadRecording reads a whole recording written by a Recorder into memory. Long recordings are
+// better read one event at a time with a RecordingReader.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	reader, err := NewRecordingReader(r)
+	if err != nil {
+		return nil, err
+	}
+	recording := &Recording{Width: reader.Width, Height: reader.Height}
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return recording, nil
+		} else if err != nil {
+			return nil, err
+		}
+		recording.Events = append(recording.Events, event)
+	}
+}
+
+// RecordingReader reads the events of a recording one at a time, so that a recording of any
+// length can be replayed without holding it in memory.
+type RecordingReader struct {
+	Width, Height int
+	source        io.Reader
+	counter       *countingReader
+	reader        recordingReader
+}
+
+// countingReader counts the bytes read through it, to know where in the source each event starts.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.Reader.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewRecordingReader reads the header of a recording written by a Recorder.
+func NewRecordingReader(r io.Reader) (*RecordingReader, error) {
+	counter := &countingReader{Reader: r}
+	reader := recordingReader{Reader: bufio.NewReader(counter)}
+
+	magic := make([]byte, len(recordingMagic)+1)
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic[:len(recordingMagic)]) != recordingMagic {
+		return nil, errors.New("not a recording")
+	}
+	reader.version = magic[len(recordingMagic)]
+	if reader.version < 1 || reader.version > recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %v", reader.version)
+	}
+	width, height := reader.int(), reader.int()
+	if reader.err != nil {
+		return nil, reader.err
+	} else if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid recording size %vx%v", width, height)
+	}
+	reader.width = width
+	return &RecordingReader{Width: width, Height: height, source: r, counter: counter, reader: reader}, nil
+}
+
+// Next returns the next event of the recording, or io.EOF after the last one.
+func (r *RecordingReader) Next() (Event, error) {
+	reader := &r.reader
+	tag, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var event Event
+	switch tag {
+	case tagAliveCellsCount:
+		event = AliveCellsCount{reader.int(), reader.int()}
+	case tagImageOutputComplete:
+		event = ImageOutputComplete{reader.int(), reader.string()}
+	case tagStateChange:
+		stateChange := StateChange{CompletedTurns: reader.int(), NewState: State(reader.byte())}
+		if reader.version >= 2 {
+			stateChange.TurnsPerSecond = reader.float()
+		}
+		event = stateChange
+	case tagCellFlipped:
+		event = CellFlipped{reader.int(), reader.cell(reader.int())}
+	case tagCellsFlipped:
+		event = CellsFlipped{reader.int(), reader.cells()}
+	case tagTurnComplete:
+		event = TurnComplete{reader.int()}
+	case tagFinalTurnComplete:
+		event = FinalTurnComplete{reader.int(), reader.cells()}
+	case tagErrorOccurred:
+		event = ErrorOccurred{reader.int(), errors.New(reader.string())}
+	case tagJSON:
+		event, err = UnmarshalEvent([]byte(reader.string()))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown event tag %v in recording", tag)
+	}
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return event, nil
+}
+
+// offset returns where in the source the next event starts.
+func (r *RecordingReader) offset() int64 {
+	return r.counter.n - int64(r.reader.Buffered())
+}
+
+// seek moves to an offset returned by offset, which needs the source to be an io.Seeker.
+func (r *RecordingReader) seek(offset int64) error {
+	seeker, ok := r.source.(io.Seeker)
+	if !ok {
+		return errors.New("the recording can't be seeked")
+	}
+	if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.counter.n = offset
+	r.reader.Reset(r.counter)
+	r.reader.err = nil
+	return nil
+}
+
+// recordingReader decodes the fields of a recording, remembering the first error it hits.
+type recordingReader struct {
+	*bufio.Reader
+	version byte
+	width   int
+	err     error
+}
+
+func (r *recordingReader) int() int {
+	v, err := binary.ReadUvarint(r)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("truncated recording: %w", err)
+	}
+	return int(v)
+}
+
+func (r *recordingReader) byte() byte {
+	b, err := r.ReadByte()
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("truncated recording: %w", err)
+	}
+	return b
+}
+
+func (r *recordingReader) float() float64 {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil && r.err == nil {
+		r.err = fmt.Errorf("truncated recording: %w", err)
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+}
+
+func (r *recordingReader) string() string {
+	b := make([]byte, r.int())
+	if _, err := io.ReadFull(r, b); err != nil && r.err == nil {
+		r.err = fmt.Errorf("truncated recording: %w", err)
+	}
+	return string(b)
+}
+
+func (r *recordingReader) cell(index int) util.Cell {
+	return util.Cell{X: index % r.width, Y: index / r.width}
+}
+
+func (r *recordingReader) cells() []util.Cell {
+	n := r.int()
+	if r.err != nil {
+		return nil
+	}
+	var cells []util.Cell
+	index := 0
+	for i := 0; i < n && r.err == nil; i++ {
+		delta, err := binary.ReadVarint(r)
+		if err != nil {
+			r.err = fmt.Errorf("truncated recording: %w", err)
+		}
+		index += int(delta)
+		cells = append(cells, r.cell(index))
+	}
+	return cells
+}
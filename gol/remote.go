@@ -0,0 +1,158 @@
+package gol
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/metrics"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// initialRetryDelay is how long dial waits before its first retry.
+const initialRetryDelay = 100 * time.Millisecond
+
+// remoteEngine sends each turn to one or more servers, which compute it with ProcessGameOfLife.
+// With several servers the world is split into strips of rows, one per server. Each strip is sent
+// with the row above and below it, so that its own rows can be computed without the rest of the world.
+type remoteEngine struct {
+	servers       []remoteServer
+	width, height int
+	rule          util.Rule
+}
+
+type remoteServer struct {
+	address      string
+	client       *rpc.Client
+	startY, endY int
+	rpcSeconds   *metrics.Histogram
+}
+
+func dialRemoteEngine(ctx context.Context, p Params, rule util.Rule, m *runMetrics) (*remoteEngine, error) {
+	e := &remoteEngine{width: p.ImageWidth, height: p.ImageHeight, rule: rule}
+	for i, address := range p.Servers {
+		client, err := dial(ctx, address, p.DialTimeout, p.DialRetries, m.rpcBytes)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.servers = append(e.servers, remoteServer{
+			address:    address,
+			client:     client,
+			startY:     i * p.ImageHeight / len(p.Servers),
+			endY:       (i + 1) * p.ImageHeight / len(p.Servers),
+			rpcSeconds: m.rpcSeconds,
+		})
+	}
+	return e, nil
+}
+
+// dial connects to a server, retrying up to retries times with a doubling delay between attempts.
+// The bytes transferred are added to rpcBytes.
+func dial(ctx context.Context, address string, timeout time.Duration, retries int, rpcBytes *metrics.Counter) (*rpc.Client, error) {
+	delay := initialRetryDelay
+	for attempt := 0; ; attempt++ {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err == nil {
+			return rpc.NewClient(metrics.CountConn(conn, rpcBytes, address)), nil
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("cannot reach server %v after %v attempts: %w", address, attempt+1, err)
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (e *remoteEngine) Step(ctx context.Context, world [][]byte) ([][]byte, []util.Cell, error) {
+	next := make([][]byte, e.height)
+	errs := make(chan error, len(e.servers))
+	for _, server := range e.servers {
+		go func(server remoteServer) {
+			errs <- server.step(ctx, world, next, e.width, e.height, e.rule)
+		}(server)
+	}
+	var err error
+	for range e.servers {
+		if serverErr := <-errs; err == nil {
+			err = serverErr
+		}
+	}
+	if err != nil {
+		return world, nil, err
+	}
+
+	var flipped []util.Cell
+	for y := range world {
+		for x := range world[y] {
+			if world[y][x] != next[y][x] {
+				flipped = append(flipped, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return next, flipped, nil
+}
+
+// step computes the server's strip of next from world.
+func (s remoteServer) step(ctx context.Context, world, next [][]byte, width, height int, rule util.Rule) error {
+	if s.startY == s.endY {
+		return nil
+	}
+	strip := make([][]byte, 0, s.endY-s.startY+2)
+	strip = append(strip, world[(s.startY+height-1)%height])
+	strip = append(strip, world[s.startY:s.endY]...)
+	strip = append(strip, world[s.endY%height])
+
+	request := stubs.Request{Grid: strip, Width: width, Height: len(strip), Turns: 1, Rule: rule}
+	response := new(stubs.Response)
+	if err := s.call(ctx, stubs.ProcessGameOfLife, request, response); err != nil {
+		if err == ctx.Err() {
+			return err
+		}
+		return fmt.Errorf("server %v: %w", s.address, err)
+	}
+	copy(next[s.startY:s.endY], response.Grid[1:len(response.Grid)-1])
+	return nil
+}
+
+func (e *remoteEngine) Close() error {
+	var err error
+	for _, server := range e.servers {
+		if closeErr := server.client.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Kill shuts every server down. A server may exit before its reply arrives, which isn't an error.
+func (e *remoteEngine) Kill() error {
+	for _, server := range e.servers {
+		err := server.client.Call(stubs.Shutdown, stubs.Request{}, new(stubs.Response))
+		if err != nil && err != io.ErrUnexpectedEOF && err != rpc.ErrShutdown {
+			return fmt.Errorf("server %v: %w", server.address, err)
+		}
+	}
+	return nil
+}
+
+// call makes an RPC call to the server, giving up on it if ctx is cancelled first.
+// The latency of calls that complete is recorded in gol_rpc_duration_seconds.
+func (s remoteServer) call(ctx context.Context, method string, request stubs.Request, response *stubs.Response) error {
+	start := time.Now()
+	select {
+	case done := <-s.client.Go(method, request, response, make(chan *rpc.Call, 1)).Done:
+		s.rpcSeconds.Observe(time.Since(start).Seconds(), s.address, method)
+		return done.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
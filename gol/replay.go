@@ -0,0 +1,267 @@
+package gol
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// DefaultReplaySpeed is the number of turns per second a recording is replayed at.
+const DefaultReplaySpeed = 30.0
+
+// replayFrame is where in a recording everything sent on the way from the previous turn to this
+// one is stored, between the offsets start and end. Frames are read when they're needed rather than
+// held in memory, so only their offsets are kept.
+type replayFrame struct {
+	turn       int
+	start, end int64
+}
+
+// indexFrames reads through a recording to find its frames. The first frame is the initial world,
+// which ends at the first StateChange, and every following frame ends with a TurnComplete. Events
+// after the last TurnComplete are indexed separately as the ending of the recording.
+func indexFrames(reader *RecordingReader) (frames []replayFrame, ending replayFrame, err error) {
+	current := replayFrame{start: reader.offset()}
+	started := false
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, replayFrame{}, err
+		}
+		after := reader.offset()
+		switch e := event.(type) {
+		case StateChange:
+			if !started {
+				current.end = after
+				frames = append(frames, current)
+				current = replayFrame{start: after}
+				started = true
+			}
+		case TurnComplete:
+			if !started {
+				current.end = after
+				frames = append(frames, current)
+				current = replayFrame{start: after}
+				started = true
+			}
+			current.turn, current.end = e.CompletedTurns, after
+			frames = append(frames, current)
+			current = replayFrame{start: after}
+		}
+	}
+	current.end = reader.offset()
+	if !started {
+		return append(frames, current), replayFrame{}, nil
+	}
+	return frames, current, nil
+}
+
+// replayer is the state of a recording being replayed.
+type replayer struct {
+	reader  *RecordingReader
+	frames  []replayFrame
+	current int
+	events  chan<- Event
+}
+
+func (r *replayer) turn() int {
+	return r.frames[r.current].turn
+}
+
+// read returns the cells flipped in a frame along with any other events in it that are replayed.
+// State changes and turns are left out, since the replay sends its own.
+func (r *replayer) read(frame replayFrame) (flips []util.Cell, others []Event, err error) {
+	if err := r.reader.seek(frame.start); err != nil {
+		return nil, nil, err
+	}
+	for r.reader.offset() < frame.end {
+		event, err := r.reader.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch e := event.(type) {
+		case CellFlipped:
+			flips = append(flips, e.Cell)
+		case CellsFlipped:
+			flips = append(flips, e.Cells...)
+		case StateChange, TurnComplete, FinalTurnComplete:
+		default:
+			others = append(others, event)
+		}
+	}
+	return flips, others, nil
+}
+
+// seek moves the replay to the given frame and renders it. Moving backwards re-sends the cells
+// flipped by each frame on the way, since flipping a cell twice restores it.
+func (r *replayer) seek(frame int) error {
+	if frame < 0 {
+		frame = 0
+	} else if frame >= len(r.frames) {
+		frame = len(r.frames) - 1
+	}
+	for r.current < frame {
+		turn := r.turn()
+		r.current++
+		flips, others, err := r.read(r.frames[r.current])
+		if err != nil {
+			return err
+		}
+		if len(flips) > 0 {
+			r.events <- CellsFlipped{turn, flips}
+		}
+		for _, event := range others {
+			r.events <- event
+		}
+	}
+	for r.current > frame {
+		flips, _, err := r.read(r.frames[r.current])
+		if err != nil {
+			return err
+		}
+		if len(flips) > 0 {
+			r.events <- CellsFlipped{r.turn(), flips}
+		}
+		r.current--
+	}
+	r.events <- TurnComplete{r.turn()}
+	return nil
+}
+
+// replaySpeed limits a replay speed to the same range as the turns per second of a run.
+func replaySpeed(speed float64) float64 {
+	return math.Max(minTurnsPerSecond, math.Min(speed, maxTurnsPerSecond))
+}
+
+// Replay plays a recording into the events channel as if it were a live run, so that it can be
+// viewed with sdl.Run without the engine. The recording is read a frame at a time as it's played,
+// so its source must be an io.Seeker such as an *os.File. It responds to the following key presses:
+//
+//	p      pause and resume
+//	n, b   step one turn forwards or backwards, pausing playback
+//	[, ]   halve or double the speed, between minTurnsPerSecond and maxTurnsPerSecond
+//	0-9    seek to 0% to 90% of the recording, pausing playback
+//	q      quit
+//
+// Playback pauses at the end of the recording so that it can still be stepped through. If the
+// recording can't be read, an ErrorOccurred is sent and the replay quits.
+func Replay(reader *RecordingReader, speed float64, events chan<- Event, keyPresses <-chan rune) {
+	if speed <= 0 {
+		speed = DefaultReplaySpeed
+	}
+	speed = replaySpeed(speed)
+	r := replayer{reader: reader, events: events}
+	quit := func(err error) {
+		if err != nil {
+			events <- ErrorOccurred{r.turn(), err}
+		}
+		events <- StateChange{r.turn(), Quitting, speed}
+		close(events)
+	}
+
+	frames, ending, err := indexFrames(reader)
+	if err != nil {
+		events <- ErrorOccurred{0, err}
+		events <- StateChange{0, Quitting, speed}
+		close(events)
+		return
+	}
+	r.frames = frames
+
+	flips, others, err := r.read(frames[0])
+	if err != nil {
+		quit(err)
+		return
+	}
+	if len(flips) > 0 {
+		events <- CellsFlipped{0, flips}
+	}
+	for _, event := range others {
+		events <- event
+	}
+	events <- StateChange{r.turn(), Executing, speed}
+	events <- TurnComplete{r.turn()}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / speed))
+	defer ticker.Stop()
+	paused := false
+	ended := false
+
+	pause := func() {
+		if !paused {
+			paused = true
+			events <- StateChange{r.turn(), Paused, speed}
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if paused {
+				continue
+			}
+			if r.current < len(r.frames)-1 {
+				if err := r.seek(r.current + 1); err != nil {
+					quit(err)
+					return
+				}
+			}
+			if r.current == len(r.frames)-1 {
+				if !ended {
+					_, others, err := r.read(ending)
+					if err != nil {
+						quit(err)
+						return
+					}
+					for _, event := range others {
+						events <- event
+					}
+					ended = true
+				}
+				pause()
+			}
+		case key := <-keyPresses:
+			switch key {
+			case 'p':
+				if paused {
+					paused = false
+					events <- StateChange{r.turn(), Executing, speed}
+				} else {
+					pause()
+				}
+			case 'n':
+				pause()
+				err = r.seek(r.current + 1)
+			case 'b':
+				pause()
+				err = r.seek(r.current - 1)
+			case '[', ']':
+				if key == '[' {
+					speed = replaySpeed(speed / 2)
+				} else {
+					speed = replaySpeed(speed * 2)
+				}
+				ticker.Reset(time.Duration(float64(time.Second) / speed))
+				state := Executing
+				if paused {
+					state = Paused
+				}
+				events <- StateChange{r.turn(), state, speed}
+			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				pause()
+				err = r.seek(int(key-'0') * (len(r.frames) - 1) / 10)
+			case 'q':
+				quit(nil)
+				return
+			}
+			if err != nil {
+				quit(err)
+				return
+			}
+		}
+	}
+}
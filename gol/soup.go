@@ -0,0 +1,115 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// These are the values Soup.Symmetry can take, named as in apgsearch.
+const (
+	C1 = "C1" // No symmetry.
+	C2 = "C2" // Unchanged by a 180 degree rotation.
+	C4 = "C4" // Unchanged by a 90 degree rotation. Requires a square world.
+	D8 = "D8" // Unchanged by any rotation or reflection. Requires a square world.
+)
+
+// DefaultDensity is the fraction of cells alive in a soup when Soup.Density is left empty.
+const DefaultDensity = 0.5
+
+// Soup describes a random initial world. The same Soup always generates the same world.
+type Soup struct {
+	// Density is the probability that each cell is alive. Defaults to DefaultDensity if nil, so that
+	// a density of 0 can be asked for.
+	Density *float64
+	// Seed seeds the random numbers. Every seed, including 0, is used as given.
+	Seed int64
+	// Symmetry is one of C1, C2, C4 or D8. Defaults to C1.
+	Symmetry string
+}
+
+// String describes the soup in the form recorded in the header of output images.
+func (soup Soup) String() string {
+	return fmt.Sprintf("random soup seed %v density %v symmetry %v", soup.Seed, soup.density(), soup.Symmetry)
+}
+
+// withDefaults returns a copy of soup with every empty field set to its default.
+func (soup Soup) withDefaults() Soup {
+	if soup.Density == nil {
+		density := DefaultDensity
+		soup.Density = &density
+	}
+	if soup.Symmetry == "" {
+		soup.Symmetry = C1
+	}
+	return soup
+}
+
+// density returns the density of the soup, or DefaultDensity if it's empty.
+func (soup Soup) density() float64 {
+	if soup.Density == nil {
+		return DefaultDensity
+	}
+	return *soup.Density
+}
+
+// orbit returns every cell that the cell at (x, y) is mapped to by the symmetries of the soup.
+func (soup Soup) orbit(width, height, x, y int) [][2]int {
+	w, h := width-1, height-1
+	switch soup.Symmetry {
+	case C2:
+		return [][2]int{{x, y}, {w - x, h - y}}
+	case C4:
+		return [][2]int{{x, y}, {w - y, x}, {w - x, h - y}, {y, h - x}}
+	case D8:
+		return [][2]int{
+			{x, y}, {w - y, x}, {w - x, h - y}, {y, h - x},
+			{w - x, y}, {y, x}, {x, h - y}, {w - y, h - x},
+		}
+	default:
+		return [][2]int{{x, y}}
+	}
+}
+
+// generateSoup creates the initial world described by soup.
+// Cells are visited in row-major order and each cell not already set by the symmetry of an
+// earlier one draws the next random number, so the world only depends on the soup and its size.
+func generateSoup(p Params, soup Soup) ([][]byte, error) {
+	switch soup.Symmetry {
+	case C1, C2:
+	case C4, D8:
+		if p.ImageWidth != p.ImageHeight {
+			return nil, fmt.Errorf("%v symmetry requires a square world, not %vx%v", soup.Symmetry, p.ImageWidth, p.ImageHeight)
+		}
+	default:
+		return nil, fmt.Errorf("unknown symmetry %q", soup.Symmetry)
+	}
+	density := soup.density()
+	if density < 0 || density > 1 {
+		return nil, fmt.Errorf("soup density %v is not between 0 and 1", density)
+	}
+
+	world := make([][]byte, p.ImageHeight)
+	set := make([][]bool, p.ImageHeight)
+	for y := range world {
+		world[y] = make([]byte, p.ImageWidth)
+		set[y] = make([]bool, p.ImageWidth)
+	}
+
+	random := rand.New(rand.NewSource(soup.Seed))
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if set[y][x] {
+				continue
+			}
+			var val byte
+			if random.Float64() < density {
+				val = 255
+			}
+			for _, cell := range soup.orbit(p.ImageWidth, p.ImageHeight, x, y) {
+				world[cell[1]][cell[0]] = val
+				set[cell[1]][cell[0]] = true
+			}
+		}
+	}
+	return world, nil
+}
@@ -0,0 +1,160 @@
+// Command golden regenerates the fixtures in check/ that the tests compare against, stepping each
+// image with util.ReferenceStep rather than any of the engines being tested:
+//
+//	go run ./golden -turns 0,1,100 -alive 10000 images/16x16.pgm
+//
+// For every image it writes the world after each of the turns to images/WxHxT.pgm in the output
+// directory, and the number of alive cells after every turn from 1 to -alive to alive/WxH.csv.
+// Without any images it regenerates the fixtures for every image in images/. Fixtures for rules other
+// than B3/S23 must be written to a directory given with -out, so that they can't replace check/.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+func main() {
+	turnList := flag.String("turns", "0,1,100", "Write an image of the world after each of these comma separated turns.")
+	aliveTurns := flag.Int("alive", 10000, "Write the alive cells after every turn up to this one. Use 0 to skip the CSV.")
+	ruleString := flag.String("rule", gol.DefaultRule, "Specify the rule in B/S notation.")
+	outDir := flag.String("out", "check", "Write the fixtures to images/ and alive/ in this directory.")
+	flag.Parse()
+
+	rule, err := util.ParseRule(*ruleString)
+	if err != nil {
+		fail(err)
+	}
+	outGiven := false
+	flag.Visit(func(f *flag.Flag) {
+		outGiven = outGiven || f.Name == "out"
+	})
+	if defaultRule, _ := util.ParseRule(gol.DefaultRule); rule != defaultRule && !outGiven {
+		fail(fmt.Errorf("fixtures for %v must be written to a directory given with -out", rule))
+	}
+	turns, err := parseTurns(*turnList)
+	if err != nil {
+		fail(err)
+	}
+	paths := flag.Args()
+	if len(paths) == 0 {
+		if paths, err = filepath.Glob("images/*.pgm"); err != nil {
+			fail(err)
+		}
+	}
+	for _, dir := range []string{"images", "alive"} {
+		if err := os.MkdirAll(filepath.Join(*outDir, dir), 0755); err != nil {
+			fail(err)
+		}
+	}
+
+	for _, path := range paths {
+		if err := generate(path, turns, *aliveTurns, rule, *outDir); err != nil {
+			fail(fmt.Errorf("%v: %w", path, err))
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// parseTurns parses a comma separated list of turns and returns them in order.
+func parseTurns(list string) ([]int, error) {
+	var turns []int
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		turn, err := strconv.Atoi(s)
+		if err != nil || turn < 0 {
+			return nil, fmt.Errorf("invalid turn %q", s)
+		}
+		turns = append(turns, turn)
+	}
+	sort.Ints(turns)
+	return turns, nil
+}
+
+// generate steps the image at path until the last turn wanted, writing the fixtures along the way.
+func generate(path string, turns []int, aliveTurns int, rule util.Rule, outDir string) error {
+	world, err := gol.ReadPgm(path)
+	if err != nil {
+		return err
+	}
+	width, height := len(world[0]), len(world)
+	last := aliveTurns
+	if len(turns) > 0 && turns[len(turns)-1] > last {
+		last = turns[len(turns)-1]
+	}
+
+	var alive *bufio.Writer
+	if aliveTurns > 0 {
+		file, err := os.Create(filepath.Join(outDir, "alive", fmt.Sprintf("%vx%v.csv", width, height)))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		alive = bufio.NewWriter(file)
+		fmt.Fprintln(alive, "completed_turns,alive_cells")
+	}
+
+	for turn := 0; ; turn++ {
+		if len(turns) > 0 && turns[0] == turn {
+			name := filepath.Join(outDir, "images", fmt.Sprintf("%vx%vx%v.pgm", width, height, turn))
+			if err := writePgm(name, world); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %v\n", name)
+			turns = turns[1:]
+		}
+		if turn > 0 && turn <= aliveTurns {
+			fmt.Fprintf(alive, "%v,%v\n", turn, countAlive(world))
+		}
+		if turn == last {
+			break
+		}
+		world = util.ReferenceStep(world, rule)
+	}
+
+	if alive != nil {
+		if err := alive.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote alive cells for %vx%v up to turn %v\n", width, height, aliveTurns)
+	}
+	return nil
+}
+
+func countAlive(world [][]byte) int {
+	count := 0
+	for _, row := range world {
+		for _, cell := range row {
+			if cell == 255 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// writePgm writes world as a binary PGM image with the same plain header as the existing fixtures.
+func writePgm(path string, world [][]byte) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "P5\n%v %v\n255\n", len(world[0]), len(world))
+	for _, row := range world {
+		b.Write(row)
+	}
+	return os.WriteFile(path, b.Bytes(), 0644)
+}
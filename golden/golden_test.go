@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestGenerate tests that the images and alive cells written for the default rule are the same,
+// byte for byte, as the fixtures in check/.
+func TestGenerate(t *testing.T) {
+	rule, err := util.ParseRule(gol.DefaultRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []string{"16x16", "64x64"} {
+		t.Run(size, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, sub := range []string{"images", "alive"} {
+				if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := generate(filepath.Join("..", "images", size+".pgm"), []int{0, 1, 100}, 10000, rule, dir); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{
+				filepath.Join("images", size+"x0.pgm"),
+				filepath.Join("images", size+"x1.pgm"),
+				filepath.Join("images", size+"x100.pgm"),
+				filepath.Join("alive", size+".csv"),
+			} {
+				expected, err := os.ReadFile(filepath.Join("..", "check", name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(actual, expected) {
+					t.Errorf("%v differs from check/%v", name, name)
+				}
+			}
+		})
+	}
+}
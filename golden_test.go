@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestReferenceStep tests that util.ReferenceStep, which the golden command generates the fixtures
+// in check/ with, reproduces the existing fixtures.
+func TestReferenceStep(t *testing.T) {
+	for _, size := range []int{16, 64} {
+		p := gol.Params{ImageWidth: size, ImageHeight: size}
+		world := make([][]byte, size)
+		for y := range world {
+			world[y] = make([]byte, size)
+		}
+		for _, cell := range readAliveCells(fmt.Sprintf("images/%vx%v.pgm", size, size), size, size) {
+			world[cell.Y][cell.X] = 255
+		}
+		counts := readAliveCounts(size, size)
+		for turn := 1; turn <= 100; turn++ {
+			world = util.ReferenceStep(world, util.Conway)
+			alive := aliveCells(world)
+			if len(alive) != counts[turn] {
+				t.Fatalf("%vx%v: expected %v alive cells after turn %v, got %v", size, size, counts[turn], turn, len(alive))
+			}
+			if turn == 1 || turn == 100 {
+				expected := readAliveCells(fmt.Sprintf("check/images/%vx%vx%v.pgm", size, size, turn), size, size)
+				assertEqualBoard(t, alive, expected, p)
+			}
+		}
+	}
+}
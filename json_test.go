@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestJSON tests that every type of event round-trips through the JSON Lines sink.
+func TestJSON(t *testing.T) {
+	sent := []gol.Event{
+		gol.CellFlipped{CompletedTurns: 0, Cell: util.Cell{X: 1, Y: 2}},
+		gol.CellsFlipped{CompletedTurns: 0, Cells: []util.Cell{{X: 3, Y: 4}, {X: 5, Y: 6}}},
+		gol.StateChange{CompletedTurns: 0, NewState: gol.Executing},
+		gol.TurnComplete{CompletedTurns: 1},
+		gol.AliveCellsCount{CompletedTurns: 7, CellsCount: 42},
+		gol.WorldStats{CompletedTurns: 7, Stats: util.Stats{
+			Alive: 42, Births: 3, Deaths: 4, Min: util.Cell{X: 1, Y: 2}, Max: util.Cell{X: 10, Y: 11},
+			Density: [][]float64{{0.5, 0}, {0.25, 0.125}}, Components: 5,
+		}},
+		gol.ImageOutputComplete{CompletedTurns: 8, Filename: "16x16x8"},
+		gol.StateChange{CompletedTurns: 9, NewState: gol.Paused, TurnsPerSecond: 2.5},
+		gol.ErrorOccurred{CompletedTurns: 9, Err: errors.New("disk full")},
+		gol.StateChange{CompletedTurns: 9, NewState: gol.Quitting},
+		gol.FinalTurnComplete{CompletedTurns: 9, Alive: []util.Cell{{X: 0, Y: 0}}},
+	}
+
+	events := make(chan gol.Event, len(sent))
+	for _, event := range sent {
+		events <- event
+	}
+	close(events)
+
+	var buffer bytes.Buffer
+	util.Check(sdl.RunJSON(events, &buffer))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert(t, len(lines) == len(sent), "Expected %v lines, got %v", len(sent), len(lines))
+	assert(t, strings.Contains(lines[2], `"Type":"StateChange"`) && strings.Contains(lines[2], `"NewState":"Executing"`),
+		"StateChange is not tagged with its type and state name: %v", lines[2])
+
+	decoder := gol.NewEventDecoder(&buffer)
+	for i, expected := range sent {
+		event, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("ERROR: Decoding event %v: %v", i, err)
+		}
+		if e, ok := expected.(gol.ErrorOccurred); ok {
+			decoded, ok := event.(gol.ErrorOccurred)
+			assert(t, ok && decoded.CompletedTurns == e.CompletedTurns && decoded.Err.Error() == e.Err.Error(),
+				"Expected %#v, got %#v", expected, event)
+			continue
+		}
+		assert(t, reflect.DeepEqual(event, expected), "Expected %#v, got %#v", expected, event)
+	}
+	_, err := decoder.Decode()
+	assert(t, err == io.EOF, "Expected the end of the stream, got %v", err)
+
+	_, err = gol.UnmarshalEvent([]byte(`{"Type":"Unknown"}`))
+	assert(t, err != nil, "Decoding an unknown event type should fail")
+}
@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"runtime"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/metrics"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/tui"
+	"uk.ac.bris.cs/gameoflife/web"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
@@ -41,29 +50,272 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.StringVar(
+		&params.Compression,
+		"compression",
+		gol.NoCompression,
+		"Specify the compression of output images: gz or zlib. Defaults to none.")
+
+	flag.StringVar(
+		&params.Rule,
+		"rule",
+		gol.DefaultRule,
+		"Specify the rule in B/S notation. Defaults to B3/S23.")
+
+	flag.StringVar(
+		&params.OutDir,
+		"out-dir",
+		gol.DefaultOutDir,
+		"Specify the directory output images are written to. Defaults to out.")
+
+	flag.StringVar(
+		&params.FilenameTemplate,
+		"out-template",
+		gol.DefaultFilenameTemplate,
+		"Specify the name of output images using {w}, {h}, {turn}, {rule} and {timestamp}. Defaults to {w}x{h}x{turn}.")
+
+	flag.DurationVar(
+		&params.ReportInterval,
+		"report-interval",
+		gol.DefaultReportInterval,
+		"Specify how often the number of alive cells is reported. Defaults to 2s.")
+
+	flag.IntVar(
+		&params.ReportTurns,
+		"report-turns",
+		0,
+		"Report the number of alive cells after every N turns instead of every report interval.")
+
+	flag.Float64Var(
+		&params.TurnsPerSecond,
+		"tps",
+		0,
+		"Limit the number of turns per second, which can be changed with [ and ] while running. Defaults to no limit.")
+
+	flag.StringVar(
+		&params.Engine,
+		"engine",
+		gol.DefaultEngine,
+		"Specify where turns are computed: local, or remote on the server. Defaults to local.")
+
+	flag.Var(
+		(*serverList)(&params.Servers),
+		"server",
+		"Specify the address of a server for the remote engine. Repeat the flag or separate addresses with commas to split turns between several servers. Defaults to 127.0.0.1:8030.")
+
+	flag.DurationVar(
+		&params.DialTimeout,
+		"dial-timeout",
+		gol.DefaultDialTimeout,
+		"Specify how long each attempt to connect to a server may take. Defaults to 2s.")
+
+	flag.IntVar(
+		&params.DialRetries,
+		"dial-retries",
+		gol.DefaultDialRetries,
+		"Specify how many times connecting to a server is retried, with a doubling delay. Use 0 to only try once. Defaults to 3.")
+
+	random := flag.Bool(
+		"random",
+		false,
+		"Generate the initial world from a random soup instead of reading an image.")
+
+	var soup gol.Soup
+
+	density := flag.Float64(
+		"density",
+		gol.DefaultDensity,
+		"Specify the fraction of alive cells in a random soup. Defaults to 0.5.")
+
+	flag.Int64Var(
+		&soup.Seed,
+		"seed",
+		0,
+		"Specify the seed of a random soup. Defaults to a seed based on the current time.")
+
+	flag.StringVar(
+		&soup.Symmetry,
+		"symmetry",
+		gol.C1,
+		"Specify the symmetry of a random soup: C1, C2, C4 or D8. Defaults to C1.")
+
 	headless := flag.Bool(
 		"headless",
 		false,
 		"Disable the SDL window for running in a headless environment.")
 
+	terminal := flag.Bool(
+		"tui",
+		false,
+		"Show the world in the terminal instead of the SDL window.")
+
+	httpAddr := flag.String(
+		"http",
+		"",
+		"Also serve the run to browsers on this address, such as :8080.")
+
+	metricsAddr := flag.String(
+		"metrics",
+		"",
+		"Serve metrics at /metrics in the Prometheus text format on this address, such as :9100.")
+
+	jsonPath := flag.String(
+		"json",
+		"",
+		"Also write every event to this file in JSON Lines format. Use - for stdout.")
+
+	recordPath := flag.String(
+		"record",
+		"",
+		"Also record every event to this file for replaying later.")
+
+	replayPath := flag.String(
+		"replay",
+		"",
+		"Replay a recording in the SDL window instead of running the engine.")
+
+	replaySpeed := flag.Float64(
+		"replay-speed",
+		gol.DefaultReplaySpeed,
+		"Specify the number of turns per second to replay at. Defaults to 30.")
+
 	flag.Parse()
 
+	if *random {
+		// Any seed can be given, including 0, so only pick one if -seed wasn't given at all.
+		seeded := false
+		flag.Visit(func(f *flag.Flag) {
+			seeded = seeded || f.Name == "seed"
+		})
+		if !seeded {
+			soup.Seed = time.Now().UnixNano()
+		}
+		soup.Density = density
+		params.Soup = &soup
+	}
+
+	var recording *gol.RecordingReader
+	if *replayPath != "" {
+		file, err := os.Open(*replayPath)
+		if err == nil {
+			defer file.Close()
+			recording, err = gol.NewRecordingReader(file)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		params.ImageWidth, params.ImageHeight = recording.Width, recording.Height
+	}
+
+	var recordOut *os.File
+	if *recordPath != "" {
+		file, err := os.Create(*recordPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		recordOut = file
+	}
+
+	var jsonOut *os.File
+	if *jsonPath == "-" {
+		// Keep stdout for the event stream and send everything else that is printed to stderr.
+		jsonOut, os.Stdout = os.Stdout, os.Stderr
+	} else if *jsonPath != "" {
+		file, err := os.Create(*jsonPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		jsonOut = file
+	}
+
 	fmt.Printf("%-10v %v\n", "Threads", params.Threads)
 	fmt.Printf("%-10v %v\n", "Width", params.ImageWidth)
 	fmt.Printf("%-10v %v\n", "Height", params.ImageHeight)
 	fmt.Printf("%-10v %v\n", "Turns", params.Turns)
+	fmt.Printf("%-10v %v\n", "Rule", params.Rule)
+	fmt.Printf("%-10v %v\n", "Engine", params.Engine)
+	if params.Engine == gol.RemoteEngine && len(params.Servers) > 0 {
+		fmt.Printf("%-10v %v\n", "Servers", strings.Join(params.Servers, ", "))
+	}
+	if params.Soup != nil {
+		fmt.Printf("%-10v %v\n", "Seed", params.Soup.Seed)
+	}
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
+	var edits chan gol.Edit
 
 	go sigterm(keyPresses)
 
-	go gol.Run(params, events, keyPresses)
-	if !(*headless) {
-		sdl.Run(params, events, keyPresses)
+	if recording != nil {
+		go gol.Replay(recording, *replaySpeed, events, keyPresses)
 	} else {
-		sdl.RunHeadless(events)
+		edits = make(chan gol.Edit, 100)
+		options := []gol.Option{gol.WithEvents(events), gol.WithKeyPresses(keyPresses), gol.WithEdits(edits), gol.WithMetrics(metrics.Default)}
+		if *terminal {
+			// The terminal viewer draws over stdout and shows the images written itself.
+			options = append(options, gol.WithProgress(io.Discard))
+		}
+		go gol.RunContext(context.Background(), params, options...)
+	}
+
+	// Every sink gets its own subscription so that a slow one can't hold up the others or the engine.
+	// Sinks that need every event queue them, while viewers only need the latest world and skip frames.
+	broker := gol.NewBroker()
+	var sinks sync.WaitGroup
+	if recordOut != nil {
+		recordEvents := broker.Subscribe(1000, gol.Unbounded).Events
+		sinks.Add(1)
+		go func() {
+			defer sinks.Done()
+			if err := sdl.RunRecord(params, recordEvents, recordOut); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
 	}
+	if jsonOut != nil {
+		jsonEvents := broker.Subscribe(1000, gol.Unbounded).Events
+		sinks.Add(1)
+		go func() {
+			defer sinks.Done()
+			if err := sdl.RunJSON(jsonEvents, jsonOut); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+	if *metricsAddr != "" {
+		http.Handle("/metrics", metrics.Default)
+		go func() {
+			fmt.Fprintln(os.Stderr, http.ListenAndServe(*metricsAddr, nil))
+		}()
+	}
+	if *httpAddr != "" {
+		webEvents := broker.Subscribe(1000, gol.Drop).Events
+		go func() {
+			if err := web.ListenAndServe(*httpAddr, params, webEvents, keyPresses); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+	viewerEvents := broker.Subscribe(1000, gol.Drop).Events
+	go broker.Run(events)
+
+	if *terminal {
+		if err := tui.Run(params, viewerEvents, keyPresses); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			sdl.RunHeadless(params, viewerEvents)
+		}
+	} else if !(*headless) {
+		sdl.Run(params, viewerEvents, keyPresses, edits)
+	} else {
+		sdl.RunHeadless(params, viewerEvents)
+	}
+	sinks.Wait()
 }
 
 func sigterm(keyPresses chan<- rune) {
@@ -72,3 +324,19 @@ func sigterm(keyPresses chan<- rune) {
 	<-sigterm
 	keyPresses <- 'q'
 }
+
+// serverList collects the addresses given by repeated or comma separated -server flags.
+type serverList []string
+
+func (s *serverList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *serverList) Set(value string) error {
+	for _, address := range strings.Split(value, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			*s = append(*s, address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// Package metrics keeps counters, gauges and histograms and serves them over HTTP in the Prometheus
+// text format, so that long runs can be graphed by existing monitoring.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry that the server records to, and that the controller records its runs to.
+var Default = NewRegistry()
+
+// DefaultBuckets are the upper bounds of histogram buckets suited to durations in seconds,
+// from 10µs to about 10s.
+var DefaultBuckets = ExponentialBuckets(0.00001, 4, 11)
+
+// ExponentialBuckets returns count bucket bounds, the first of which is start and each of which
+// is factor times the one before it.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// Registry is a set of metrics. It's an http.Handler that writes every metric in the order they
+// were created. Creating a metric with the name of one that already exists returns the existing
+// one, so that everything recording to the same registry shares it.
+type Registry struct {
+	mutex    sync.Mutex
+	families []*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// family is a metric along with the values it has for each combination of labels.
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64
+	mutex            sync.Mutex
+	series           map[string]*series
+}
+
+// series is the value of a metric for one combination of labels. Counters and gauges only use value.
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+func (r *Registry) newFamily(name, help, kind string, buckets []float64, labels []string) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, f := range r.families {
+		if f.name != name {
+			continue
+		}
+		if f.kind != kind || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("metrics: %v already exists as a %v with labels %v", name, f.kind, f.labels))
+		}
+		return f
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	if len(labels) == 0 {
+		f.get(nil)
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+// get returns the series for the label values, creating it if needed. f.mutex must be held, except
+// while the family is being created.
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %v has labels %v but was given %v", f.name, f.labels, labelValues))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), labelValues...), counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter is a value that only goes up, such as the number of turns completed.
+type Counter struct{ f *family }
+
+// NewCounter creates a counter. If there are labels, a value for each of them must be given
+// whenever the counter is changed.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.newFamily(name, help, "counter", nil, labels)}
+}
+
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.f.mutex.Lock()
+	c.f.get(labelValues).value += v
+	c.f.mutex.Unlock()
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Gauge is a value that can go up and down, such as the number of alive cells.
+type Gauge struct{ f *family }
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.newFamily(name, help, "gauge", nil, labels)}
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mutex.Lock()
+	g.f.get(labelValues).value = v
+	g.f.mutex.Unlock()
+}
+
+// Histogram counts observations, such as latencies, in buckets by their upper bounds.
+type Histogram struct{ f *family }
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.newFamily(name, help, "histogram", buckets, labels)}
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mutex.Lock()
+	s := h.f.get(labelValues)
+	if i := sort.SearchFloat64s(h.f.buckets, v); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.value += v
+	s.count++
+	h.f.mutex.Unlock()
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mutex.Unlock()
+
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+func (f *family) write(b *strings.Builder) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	fmt.Fprintf(b, "# HELP %v %v\n", f.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(f.help))
+	fmt.Fprintf(b, "# TYPE %v %v\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%v%v %v\n", f.name, f.labelSet(s.labels, ""), formatFloat(s.value))
+			continue
+		}
+		cumulative := uint64(0)
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%v_bucket%v %v\n", f.name, f.labelSet(s.labels, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(b, "%v_bucket%v %v\n", f.name, f.labelSet(s.labels, "+Inf"), s.count)
+		fmt.Fprintf(b, "%v_sum%v %v\n", f.name, f.labelSet(s.labels, ""), formatFloat(s.value))
+		fmt.Fprintf(b, "%v_count%v %v\n", f.name, f.labelSet(s.labels, ""), s.count)
+	}
+}
+
+// labelSet formats the labels of a series, followed by the le label of a histogram bucket if there is one.
+func (f *family) labelSet(values []string, le string) string {
+	var pairs []string
+	for i, label := range f.labels {
+		pairs = append(pairs, label+"="+quote(values[i]))
+	}
+	if le != "" {
+		pairs = append(pairs, "le="+quote(le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingConn adds the bytes read from and written to a connection to a counter.
+type countingConn struct {
+	net.Conn
+	counter        *Counter
+	received, sent []string
+}
+
+// CountConn returns a connection that adds the bytes read from and written to conn to counter,
+// labelled with labelValues followed by "received" or "sent".
+func CountConn(conn net.Conn, counter *Counter, labelValues ...string) net.Conn {
+	received := append(append([]string(nil), labelValues...), "received")
+	sent := append(append([]string(nil), labelValues...), "sent")
+	return &countingConn{conn, counter, received, sent}
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.counter.Add(float64(n), c.received...)
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.counter.Add(float64(n), c.sent...)
+	return n, err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/metrics"
+)
+
+// TestMetricsFormat tests that metrics are written in the Prometheus text format.
+func TestMetricsFormat(t *testing.T) {
+	r := metrics.NewRegistry()
+	turns := r.NewCounter("turns_total", "Turns completed.")
+	bytes := r.NewCounter("bytes_total", "Bytes \"transferred\".", "direction")
+	latency := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "method")
+	turns.Add(3)
+	bytes.Add(10, "sent")
+	bytes.Add(5, "received")
+	latency.Observe(0.05, "Step")
+	latency.Observe(0.5, "Step")
+	latency.Observe(5, "Step")
+
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	expected := `# HELP turns_total Turns completed.
+# TYPE turns_total counter
+turns_total 3
+# HELP bytes_total Bytes "transferred".
+# TYPE bytes_total counter
+bytes_total{direction="received"} 5
+bytes_total{direction="sent"} 10
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="Step",le="0.1"} 1
+latency_seconds_bucket{method="Step",le="1"} 2
+latency_seconds_bucket{method="Step",le="+Inf"} 3
+latency_seconds_sum{method="Step"} 5.55
+latency_seconds_count{method="Step"} 3
+`
+	assert(t, response.Body.String() == expected, "Expected\n%v\ngot\n%v", expected, response.Body.String())
+	assert(t, strings.HasPrefix(response.Header().Get("Content-Type"), "text/plain"), "Unexpected content type %q", response.Header().Get("Content-Type"))
+}
+
+// metricValue returns the value of a series in metrics written in the Prometheus text format.
+func metricValue(t *testing.T, r *metrics.Registry, series string) float64 {
+	var text strings.Builder
+	_, _ = r.WriteTo(&text)
+	match := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(series) + ` (\S+)$`).FindStringSubmatch(text.String())
+	if match == nil {
+		t.Fatalf("%v is missing from the metrics:\n%v", series, text.String())
+	}
+	v, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// TestMetrics tests that a run records the turns completed, the alive cells and the step time of every worker.
+func TestMetrics(t *testing.T) {
+	r := metrics.NewRegistry()
+	p := gol.Params{Turns: 20, Threads: 2, ImageWidth: 64, ImageHeight: 64, ReportTurns: 10, Soup: &gol.Soup{Seed: 1}}
+	world, err := gol.RunContext(context.Background(), p, gol.WithMetrics(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	turns := metricValue(t, r, "gol_turns_completed_total")
+	assert(t, turns == 20, "Expected 20 turns completed, got %v", turns)
+	alive := 0
+	for _, row := range world {
+		for _, cell := range row {
+			if cell == 255 {
+				alive++
+			}
+		}
+	}
+	assert(t, metricValue(t, r, "gol_alive_cells") == float64(alive), "Expected gol_alive_cells to be %v", alive)
+	for _, worker := range []string{"0", "1"} {
+		count := metricValue(t, r, `gol_worker_step_seconds_count{worker="`+worker+`"}`)
+		assert(t, count == 20, "Expected worker %v to have stepped 20 times, got %v", worker, count)
+	}
+}
+
+// TestMetricsConcurrentRuns tests that concurrent runs recording to their own registries don't
+// change each other's metrics.
+func TestMetricsConcurrentRuns(t *testing.T) {
+	registries := make([]*metrics.Registry, 4)
+	errs := make(chan error, len(registries))
+	for i := range registries {
+		registries[i] = metrics.NewRegistry()
+		p := gol.Params{Turns: 10 * (i + 1), Threads: 2, ImageWidth: 64, ImageHeight: 64, ReportTurns: 5, Soup: &gol.Soup{Seed: int64(i)}}
+		go func(r *metrics.Registry) {
+			_, err := gol.RunContext(context.Background(), p, gol.WithMetrics(r))
+			errs <- err
+		}(registries[i])
+	}
+	for range registries {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, r := range registries {
+		turns := metricValue(t, r, "gol_turns_completed_total")
+		assert(t, turns == float64(10*(i+1)), "Run %v expected %v turns completed, got %v", i, 10*(i+1), turns)
+	}
+}
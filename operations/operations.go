@@ -0,0 +1,167 @@
+// Package operations implements the GolOperations RPC service that the remote engine calls, so that
+// it can be served by the server command or started in-process, such as by tests.
+package operations
+
+import (
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/metrics"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// helper functions
+func makeImmutableMatrix(matrix [][]uint8) func(y, x int) uint8 {
+	return func(y, x int) uint8 {
+		return matrix[y][x]
+	}
+}
+
+func countAliveNeighbors(width, height, turns int, data func(y, x int) uint8, x, y int) int {
+	aliveNeighbors := 0
+	for i := -1; i <= 1; i++ {
+		for j := -1; j <= 1; j++ {
+			if i == 0 && j == 0 {
+				continue
+			}
+			neighborX := (x + i + width) % width
+			neighborY := (y + j + height) % height
+			if data(neighborY, neighborX) == 255 {
+				aliveNeighbors++
+			}
+		}
+	}
+	return aliveNeighbors
+}
+
+func calculateNextState(width, height, turns int, rule util.Rule, data func(y, x int) uint8) (newWorld [][]byte, alive int) {
+	newWorld = make([][]byte, height)
+	for i := range newWorld {
+		newWorld[i] = make([]byte, width)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			aliveNeighbors := countAliveNeighbors(width, height, turns, data, x, y)
+			if rule.Next(data(y, x) == 255, aliveNeighbors) {
+				newWorld[y][x] = 255
+				alive++
+			} else {
+				newWorld[y][x] = 0
+			}
+		}
+	}
+
+	return newWorld, alive
+}
+
+// GolOperations computes turns for the remote engine. Its exported methods are the RPC calls named
+// in stubs.
+type GolOperations struct {
+	// The metrics recorded by the server.
+	turnsCompleted *metrics.Counter
+	turnsPerSecond *metrics.Gauge
+	aliveCells     *metrics.Gauge
+	stepSeconds    *metrics.Histogram
+	rpcSeconds     *metrics.Histogram
+	rpcBytes       *metrics.Counter
+
+	// processing lets only one request be processed at a time, since they share the world.
+	processing sync.Mutex
+	world      [][]byte
+
+	// shutdown is closed to stop the server once the controller asks it to.
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+// New returns a GolOperations that records its metrics to registry.
+func New(registry *metrics.Registry) *GolOperations {
+	return &GolOperations{
+		turnsCompleted: registry.NewCounter(
+			"gol_server_turns_completed_total",
+			"Turns completed by the server."),
+		turnsPerSecond: registry.NewGauge(
+			"gol_server_turns_per_second",
+			"Turns per second of the last ProcessAllTurns call."),
+		aliveCells: registry.NewGauge(
+			"gol_server_alive_cells",
+			"Alive cells in the world returned by the last ProcessAllTurns call."),
+		stepSeconds: registry.NewHistogram(
+			"gol_server_step_seconds",
+			"Time taken to compute each turn.",
+			metrics.DefaultBuckets),
+		rpcSeconds: registry.NewHistogram(
+			"gol_server_rpc_duration_seconds",
+			"Time taken to handle each RPC call.",
+			metrics.DefaultBuckets, "method"),
+		rpcBytes: registry.NewCounter(
+			"gol_server_rpc_bytes_total",
+			"Bytes sent to and received from controllers.",
+			"direction"),
+		shutdown: make(chan struct{}),
+	}
+}
+
+// observeCall records how long the call to method took since start.
+func (g *GolOperations) observeCall(method string, start time.Time) {
+	g.rpcSeconds.Observe(time.Since(start).Seconds(), method)
+}
+
+// process is the main operation, which returns the world after turns turns along with the number of
+// alive cells in it.
+func (g *GolOperations) process(width, height, turns int, rule util.Rule) ([][]byte, int) {
+	alive := 0
+	for turn := 0; turn < turns; turn++ {
+		start := time.Now()
+		immutableData := makeImmutableMatrix(g.world)
+		g.world, alive = calculateNextState(width, height, turns, rule, immutableData)
+		g.stepSeconds.Observe(time.Since(start).Seconds())
+		g.turnsCompleted.Inc()
+	}
+	return g.world, alive
+}
+
+func (g *GolOperations) ProcessAllTurns(req stubs.Request, res *stubs.Response) (err error) {
+	defer g.observeCall("ProcessAllTurns", time.Now())
+	g.processing.Lock()
+	defer g.processing.Unlock()
+	g.world = req.Grid
+	start := time.Now()
+	res.Grid, res.Alive = g.process(req.Width, req.Height, req.Turns, req.Rule)
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		g.turnsPerSecond.Set(float64(req.Turns) / elapsed)
+	}
+	g.aliveCells.Set(float64(res.Alive))
+	return
+}
+
+func (g *GolOperations) Shutdown(req stubs.Request, res *stubs.Response) (err error) {
+	defer g.observeCall("Shutdown", time.Now())
+	g.shutdownOnce.Do(func() { close(g.shutdown) })
+	return
+}
+
+// Done returns a channel that is closed once a controller has called Shutdown.
+func (g *GolOperations) Done() <-chan struct{} {
+	return g.shutdown
+}
+
+// Serve serves the RPC calls of g on every connection made to listener, counting the bytes
+// transferred, until listener is closed. Each call to Serve has its own rpc.Server, so that any
+// number of servers can run in the same process.
+func Serve(listener net.Listener, g *GolOperations) error {
+	server := rpc.NewServer()
+	if err := server.Register(g); err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeConn(metrics.CountConn(conn, g.rpcBytes))
+	}
+}
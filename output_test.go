@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestOutputTemplate tests that output images are named from the output directory and filename template.
+func TestOutputTemplate(t *testing.T) {
+	dir := t.TempDir()
+	p := gol.Params{
+		Turns:            0,
+		Threads:          1,
+		ImageWidth:       16,
+		ImageHeight:      16,
+		OutDir:           filepath.Join(dir, "results"),
+		FilenameTemplate: "{rule}/{w}x{h}-{timestamp}-{turn}",
+	}
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var filename string
+	for event := range events {
+		switch e := event.(type) {
+		case gol.ImageOutputComplete:
+			filename = e.Filename
+		case gol.ErrorOccurred:
+			t.Fatal(e)
+		}
+	}
+
+	assert(t, regexp.MustCompile(`^B3S23/16x16-\d{8}T\d{6}\.\d{3}-0$`).MatchString(filename),
+		"Filename %q does not match the template %q", filename, p.FilenameTemplate)
+	_, err := os.Stat(filepath.Join(p.OutDir, filename+".pgm"))
+	assert(t, err == nil, "Output image was not written to the output directory: %v", err)
+}
+
+// TestOutputTemplateErrors tests that bad filename templates and rules are reported before any work is done.
+func TestOutputTemplateErrors(t *testing.T) {
+	for _, p := range []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16, FilenameTemplate: "{w}x{h}x{turns}"},
+		{ImageWidth: 16, ImageHeight: 16, Rule: "B3S23"},
+	} {
+		testIoError(t, p)
+	}
+}
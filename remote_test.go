@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/metrics"
+	"uk.ac.bris.cs/gameoflife/operations"
+)
+
+// startServers starts n servers on free local ports for the rest of the test and returns their
+// addresses.
+func startServers(t *testing.T, n int) []string {
+	var addresses []string
+	for i := 0; i < n; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		go operations.Serve(listener, operations.New(metrics.NewRegistry()))
+		addresses = append(addresses, listener.Addr().String())
+	}
+	return addresses
+}
+
+// TestRemoteEngine tests the remote engine against the expected images, with the turns split between
+// one and several servers.
+func TestRemoteEngine(t *testing.T) {
+	servers := startServers(t, 3)
+	for _, servers := range [][]string{servers[:1], servers[:2], servers} {
+		p := gol.Params{
+			Turns:       100,
+			Threads:     1,
+			ImageWidth:  16,
+			ImageHeight: 16,
+			Engine:      gol.RemoteEngine,
+			Servers:     servers,
+		}
+		t.Run(fmt.Sprintf("%v servers", len(servers)), func(t *testing.T) {
+			events := make(chan gol.Event, 1000)
+			go gol.Run(p, events, nil)
+			for event := range events {
+				switch e := event.(type) {
+				case gol.FinalTurnComplete:
+					expected := readAliveCells("check/images/16x16x100.pgm", p.ImageWidth, p.ImageHeight)
+					assertEqualBoard(t, e.Alive, expected, p)
+				case gol.ErrorOccurred:
+					t.Fatal(e)
+				}
+			}
+		})
+	}
+}
+
+// TestUnreachableServer tests that failing to reach a server is reported as an error.
+func TestUnreachableServer(t *testing.T) {
+	testIoError(t, gol.Params{
+		Turns:       1,
+		Threads:     1,
+		ImageWidth:  16,
+		ImageHeight: 16,
+		Engine:      gol.RemoteEngine,
+		Servers:     []string{"127.0.0.1:1"},
+		DialRetries: 1,
+	})
+}
+
+// TestNoDialRetries tests that a DialRetries of 0 gives up after the first attempt to connect.
+func TestNoDialRetries(t *testing.T) {
+	p := gol.Params{
+		Turns:       1,
+		Threads:     1,
+		ImageWidth:  16,
+		ImageHeight: 16,
+		Engine:      gol.RemoteEngine,
+		Servers:     []string{"127.0.0.1:1"},
+	}
+	_, err := gol.RunContext(context.Background(), p)
+	assert(t, err != nil && strings.Contains(err.Error(), "after 1 attempts"),
+		"Expected connecting to be attempted once, got %v", err)
+}
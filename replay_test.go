@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestReplay tests that a recorded blinker is read back unchanged and can be stepped forwards, backwards and seeked.
+func TestReplay(t *testing.T) {
+	p := gol.Params{Turns: 4, ImageWidth: 5, ImageHeight: 5}
+	horizontal := []util.Cell{{X: 1, Y: 2}, {X: 2, Y: 2}, {X: 3, Y: 2}}
+	flips := []util.Cell{{X: 1, Y: 2}, {X: 3, Y: 2}, {X: 2, Y: 1}, {X: 2, Y: 3}}
+
+	sent := []gol.Event{
+		gol.CellsFlipped{CompletedTurns: 0, Cells: horizontal},
+		gol.StateChange{CompletedTurns: 0, NewState: gol.Executing, TurnsPerSecond: 30},
+	}
+	for turn := 1; turn <= p.Turns; turn++ {
+		sent = append(sent,
+			gol.CellsFlipped{CompletedTurns: turn - 1, Cells: flips},
+			gol.TurnComplete{CompletedTurns: turn},
+			gol.AliveCellsCount{CompletedTurns: turn, CellsCount: 3})
+	}
+	sent = append(sent,
+		gol.ImageOutputComplete{CompletedTurns: p.Turns, Filename: "5x5x4"},
+		gol.StateChange{CompletedTurns: p.Turns, NewState: gol.Quitting},
+		gol.FinalTurnComplete{CompletedTurns: p.Turns, Alive: horizontal})
+
+	events := make(chan gol.Event, len(sent))
+	for _, event := range sent {
+		events <- event
+	}
+	close(events)
+
+	var buffer bytes.Buffer
+	util.Check(sdl.RunRecord(p, events, &buffer))
+	recording, err := gol.ReadRecording(bytes.NewReader(buffer.Bytes()))
+	util.Check(err)
+	assert(t, recording.Width == p.ImageWidth && recording.Height == p.ImageHeight,
+		"Expected a %vx%v recording, got %vx%v", p.ImageWidth, p.ImageHeight, recording.Width, recording.Height)
+	assert(t, reflect.DeepEqual(recording.Events, sent), "Recorded events differ:\n%v\n%v", recording.Events, sent)
+
+	keyPresses := make(chan rune, 10)
+	replayed := make(chan gol.Event, 1000)
+	for _, key := range "pnnb90q" {
+		keyPresses <- key
+	}
+	reader, err := gol.NewRecordingReader(bytes.NewReader(buffer.Bytes()))
+	util.Check(err)
+	go gol.Replay(reader, 0.001, replayed, keyPresses)
+
+	world := make([][]byte, p.ImageHeight)
+	for i := range world {
+		world[i] = make([]byte, p.ImageWidth)
+	}
+	var turns []int
+	for event := range replayed {
+		switch e := event.(type) {
+		case gol.CellsFlipped:
+			for _, cell := range e.Cells {
+				world[cell.Y][cell.X] = ^world[cell.Y][cell.X]
+			}
+		case gol.TurnComplete:
+			turns = append(turns, e.CompletedTurns)
+			var alive []util.Cell
+			for y := range world {
+				for x := range world[y] {
+					if world[y][x] == 0xFF {
+						alive = append(alive, util.Cell{X: x, Y: y})
+					}
+				}
+			}
+			expected := horizontal
+			if e.CompletedTurns%2 == 1 {
+				expected = []util.Cell{{X: 2, Y: 1}, {X: 2, Y: 2}, {X: 2, Y: 3}}
+			}
+			assertEqualBoard(t, alive, expected, p)
+		}
+	}
+	assert(t, reflect.DeepEqual(turns, []int{0, 1, 2, 1, 3, 0}), "Replay visited turns %v", turns)
+}
+
+// TestReplaySpeed tests that changing the replay speed is limited rather than reaching a speed that
+// can't be ticked at.
+func TestReplaySpeed(t *testing.T) {
+	var buffer bytes.Buffer
+	events := make(chan gol.Event, 2)
+	events <- gol.StateChange{CompletedTurns: 0, NewState: gol.Executing}
+	events <- gol.TurnComplete{CompletedTurns: 1}
+	close(events)
+	util.Check(sdl.RunRecord(gol.Params{ImageWidth: 4, ImageHeight: 4}, events, &buffer))
+	reader, err := gol.NewRecordingReader(bytes.NewReader(buffer.Bytes()))
+	util.Check(err)
+
+	keyPresses := make(chan rune, 100)
+	for i := 0; i < 30; i++ {
+		keyPresses <- ']'
+	}
+	for i := 0; i < 40; i++ {
+		keyPresses <- '['
+	}
+	keyPresses <- 'q'
+	replayed := make(chan gol.Event, 1000)
+	go gol.Replay(reader, gol.DefaultReplaySpeed, replayed, keyPresses)
+
+	var speeds []float64
+	for event := range replayed {
+		if e, ok := event.(gol.StateChange); ok {
+			speeds = append(speeds, e.TurnsPerSecond)
+		}
+	}
+	for _, speed := range speeds {
+		assert(t, speed > 0 && speed <= 10000, "Expected a speed between 0 and 10000, got %v", speed)
+	}
+	assert(t, len(speeds) > 2 && speeds[len(speeds)-2] == 1.0/16,
+		"Expected the speed to stop at 1/16 turns per second, got %v", speeds)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestReportTurns tests that turn-based reporting sends exactly one AliveCellsCount every N turns, matching check/alive.
+func TestReportTurns(t *testing.T) {
+	for _, every := range []int{1, 7, 25} {
+		p := gol.Params{
+			Turns:       100,
+			Threads:     4,
+			ImageWidth:  64,
+			ImageHeight: 64,
+			ReportTurns: every,
+			// Make sure no report could come from the ticker instead.
+			ReportInterval: time.Hour,
+		}
+		t.Run(fmt.Sprintf("%v", every), func(t *testing.T) {
+			alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+
+			var turns []int
+			for event := range events {
+				switch e := event.(type) {
+				case gol.AliveCellsCount:
+					turns = append(turns, e.CompletedTurns)
+					assert(t, e.CellsCount == alive[e.CompletedTurns],
+						"At turn %v expected %v alive cells, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], e.CellsCount)
+				case gol.WorldStats:
+					assert(t, e.Stats.Alive == alive[e.CompletedTurns],
+						"At turn %v expected WorldStats with %v alive cells, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], e.Stats.Alive)
+				}
+			}
+
+			assert(t, len(turns) == p.Turns/every, "Expected %v AliveCellsCount events, got %v", p.Turns/every, len(turns))
+			for i, turn := range turns {
+				assert(t, turn == (i+1)*every, "AliveCellsCount %v should be for turn %v, not %v", i, (i+1)*every, turn)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package sdl
+
+import (
+	"math"
+)
+
+// ColourMode decides how cells are coloured in the window.
+type ColourMode int
+
+const (
+	// Plain shows alive cells in white.
+	Plain ColourMode = iota
+	// Age colours alive cells by how many turns they have been alive, from white when born through
+	// yellow and red to purple.
+	Age
+	// Trail shows alive cells in white and leaves a fading trail of recently dead cells.
+	Trail
+	// Heatmap colours every cell by how many times it has changed state since the start.
+	Heatmap
+	colourModes
+)
+
+func (mode ColourMode) String() string {
+	switch mode {
+	case Plain:
+		return "Plain"
+	case Age:
+		return "Age"
+	case Trail:
+		return "Trail"
+	case Heatmap:
+		return "Heatmap"
+	default:
+		return "Unknown"
+	}
+}
+
+// Next returns the colour mode after mode, cycling back to Plain after the last.
+func (mode ColourMode) Next() ColourMode {
+	return (mode + 1) % colourModes
+}
+
+// ageTurns is the age at which cells reach the last colour in the Age mode, and trailTurns the number
+// of turns a dead cell's trail takes to fade out in the Trail mode.
+const (
+	ageTurns   = 1000
+	trailTurns = 32
+)
+
+type colour struct {
+	r, g, b float64
+}
+
+var (
+	agePalette     = []colour{{255, 255, 255}, {255, 220, 0}, {230, 40, 0}, {120, 0, 160}}
+	trailPalette   = []colour{{255, 140, 0}, {120, 0, 60}, {0, 0, 0}}
+	heatmapPalette = []colour{{0, 0, 0}, {0, 0, 160}, {200, 0, 0}, {255, 200, 0}, {255, 255, 255}}
+)
+
+// gradient returns the colour a fraction t of the way along a palette of evenly spaced colours.
+func gradient(palette []colour, t float64) colour {
+	t = math.Max(0, math.Min(1, t)) * float64(len(palette)-1)
+	i := int(t)
+	if i == len(palette)-1 {
+		return palette[i]
+	}
+	f := t - float64(i)
+	a, b := palette[i], palette[i+1]
+	return colour{a.r + (b.r-a.r)*f, a.g + (b.g-a.g)*f, a.b + (b.b-a.b)*f}
+}
+
+// cellHistory records what the colour modes need to know about every cell.
+type cellHistory struct {
+	turn     int
+	changed  []int
+	flips    []uint32
+	maxFlips uint32
+}
+
+func newCellHistory(cells int) *cellHistory {
+	return &cellHistory{changed: make([]int, cells), flips: make([]uint32, cells)}
+}
+
+func (h *cellHistory) flip(i int) {
+	h.changed[i] = h.turn
+	h.flips[i]++
+	if h.flips[i] > h.maxFlips {
+		h.maxFlips = h.flips[i]
+	}
+}
+
+// recolour fills colours, in the same ARGB format as the window's pixels, with the colour of every
+// cell in the given mode.
+func (h *cellHistory) recolour(mode ColourMode, pixels, colours []byte) {
+	logMaxFlips := math.Log1p(float64(h.maxFlips))
+	for i := range h.changed {
+		alive := pixels[4*i] == 0xFF
+		var c colour
+		switch mode {
+		case Age:
+			if alive {
+				c = gradient(agePalette, math.Log1p(float64(h.turn-h.changed[i]))/math.Log1p(ageTurns))
+			}
+		case Trail:
+			if alive {
+				c = colour{255, 255, 255}
+			} else if h.flips[i] > 0 && h.turn-h.changed[i] < trailTurns {
+				c = gradient(trailPalette, float64(h.turn-h.changed[i])/trailTurns)
+			}
+		case Heatmap:
+			if logMaxFlips > 0 {
+				c = gradient(heatmapPalette, math.Log1p(float64(h.flips[i]))/logMaxFlips)
+			}
+		}
+		colours[4*i+0] = byte(c.b)
+		colours[4*i+1] = byte(c.g)
+		colours[4*i+2] = byte(c.r)
+		colours[4*i+3] = 0xFF
+	}
+}
@@ -2,6 +2,8 @@ package sdl
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"time"
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/gol"
@@ -10,19 +12,48 @@ import (
 
 const FPS = 60
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
+// panStep is how many screen pixels an arrow key pans by, and wheelZoom how much one step of the
+// mouse wheel zooms by.
+const (
+	panStep   = 32
+	wheelZoom = 1.25
+)
+
+// Run shows the world in a window, sending key presses to the engine. While paused the world can be
+// edited by clicking or dragging with the left mouse button, which sets every cell it passes over to
+// the opposite of the first cell clicked. Edits are only sent if edits isn't nil.
+//
+// The view is controlled without involving the engine: the mouse wheel or + and - zoom, dragging with
+// the right or middle mouse button or the arrow keys pan, f fits the world to the window and g
+// toggles grid lines at high zoom. m cycles through the colour modes and h shows or hides the HUD with
+// the turn, population, throughput, estimated time left, state, rule and zoom.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, edits chan<- gol.Edit) {
 	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
 	defer w.Destroy()
 	dirty := false
 	refreshTicker := time.NewTicker(time.Second / time.Duration(FPS))
-	avgTurns := util.NewAvgTurns()
+	throughput := util.NewThroughput(p.ImageWidth*p.ImageHeight, p.Turns)
+	paused := false
+	drawing, drawAlive := false, false
+	panning := false
+	showHUD := true
+	turn, state := 0, "Starting"
+	rule := p.Rule
+	if rule == "" {
+		rule = gol.DefaultRule
+	}
+
+	draw := func(x, y int32) {
+		if cell, ok := w.CellAt(x, y); ok && w.PixelSet(cell.X, cell.Y) != drawAlive {
+			edits <- gol.Edit{Cell: cell, Alive: drawAlive}
+		}
+	}
 
 sdl:
 	for {
 		select {
 		case <-refreshTicker.C:
-			event := w.PollEvent()
-			if event != nil {
+			for event := w.PollEvent(); event != nil; event = w.PollEvent() {
 				switch e := event.(type) {
 				case *sdl.QuitEvent:
 					keyPresses <- 'q'
@@ -38,10 +69,92 @@ sdl:
 						keyPresses <- 'q'
 					case sdl.K_k:
 						keyPresses <- 'k'
+					case sdl.K_c:
+						keyPresses <- 'c'
+					case sdl.K_r:
+						keyPresses <- 'r'
+					case sdl.K_n:
+						keyPresses <- 'n'
+					case sdl.K_b:
+						keyPresses <- 'b'
+					case sdl.K_LEFTBRACKET:
+						keyPresses <- '['
+					case sdl.K_RIGHTBRACKET:
+						keyPresses <- ']'
+					case sdl.K_0, sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4, sdl.K_5, sdl.K_6, sdl.K_7, sdl.K_8, sdl.K_9:
+						keyPresses <- rune('0' + e.Keysym.Sym - sdl.K_0)
+					case sdl.K_EQUALS, sdl.K_PLUS, sdl.K_KP_PLUS:
+						w.ZoomCentre(2)
+					case sdl.K_MINUS, sdl.K_KP_MINUS:
+						w.ZoomCentre(0.5)
+					case sdl.K_f:
+						w.FitToWindow()
+					case sdl.K_g:
+						w.ToggleGrid()
+					case sdl.K_h:
+						showHUD = !showHUD
+					case sdl.K_m:
+						fmt.Printf("Colour mode %v\n", w.CycleColourMode())
+					case sdl.K_LEFT:
+						w.Pan(panStep, 0)
+					case sdl.K_RIGHT:
+						w.Pan(-panStep, 0)
+					case sdl.K_UP:
+						w.Pan(0, panStep)
+					case sdl.K_DOWN:
+						w.Pan(0, -panStep)
+					}
+					dirty = true
+				case *sdl.MouseButtonEvent:
+					switch e.Button {
+					case sdl.BUTTON_LEFT:
+						cell, ok := w.CellAt(e.X, e.Y)
+						if e.State == sdl.PRESSED && ok && paused && edits != nil {
+							drawing, drawAlive = true, !w.PixelSet(cell.X, cell.Y)
+							draw(e.X, e.Y)
+						} else {
+							drawing = false
+						}
+					case sdl.BUTTON_RIGHT, sdl.BUTTON_MIDDLE:
+						panning = e.State == sdl.PRESSED
+					}
+				case *sdl.MouseMotionEvent:
+					if drawing && paused {
+						draw(e.X, e.Y)
+					}
+					if panning {
+						w.Pan(e.XRel, e.YRel)
+						dirty = true
+					}
+				case *sdl.MouseWheelEvent:
+					scroll := e.Y
+					if e.Direction == sdl.MOUSEWHEEL_FLIPPED {
+						scroll = -scroll
+					}
+					x, y, _ := sdl.GetMouseState()
+					w.Zoom(math.Pow(wheelZoom, float64(scroll)), x, y)
+					dirty = true
+				case *sdl.WindowEvent:
+					if e.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+						dirty = true
 					}
 				}
 			}
 			if dirty {
+				if showHUD {
+					hud := []string{
+						fmt.Sprintf("Turn %v", turn),
+						fmt.Sprintf("Alive %v", w.Alive()),
+						fmt.Sprintf("Rate %.1f turns/s", throughput.TurnsPerSecond()),
+						fmt.Sprintf("Cells %v/s", util.FormatSI(throughput.CellsPerSecond())),
+					}
+					if eta, ok := throughput.ETA(); ok {
+						hud = append(hud, fmt.Sprintf("ETA %v", eta))
+					}
+					w.SetHUD(append(hud, state, fmt.Sprintf("Rule %v", rule), fmt.Sprintf("Zoom %.2fx", w.ZoomLevel())))
+				} else {
+					w.SetHUD(nil)
+				}
 				w.RenderFrame()
 				dirty = false
 			}
@@ -52,21 +165,35 @@ sdl:
 			}
 			switch e := event.(type) {
 			case gol.CellFlipped:
+				w.SetTurn(e.CompletedTurns)
 				w.FlipPixel(e.Cell.X, e.Cell.Y)
 			case gol.CellsFlipped:
+				w.SetTurn(e.CompletedTurns)
 				for _, cell := range e.Cells {
-					w.FlipPixel(cell.X, cell.Y) 
+					w.FlipPixel(cell.X, cell.Y)
+				}
+				// Edits are made between turns, so show them straight away rather than at the next TurnComplete.
+				if paused {
+					dirty = true
 				}
 			case gol.TurnComplete:
+				w.SetTurn(e.CompletedTurns)
+				turn = e.CompletedTurns
 				dirty = true
 			case gol.AliveCellsCount:
-				fmt.Printf("Completed Turns %-8v %-20v Avg%+5v turns/sec\n", event.GetCompletedTurns(), event, avgTurns.Get(event.GetCompletedTurns()))
+				throughput.Update(event.GetCompletedTurns())
+				fmt.Printf("Completed Turns %-8v %-20v %v\n", event.GetCompletedTurns(), event, throughput)
 			case gol.FinalTurnComplete:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			case gol.ImageOutputComplete:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+			case gol.ErrorOccurred:
+				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			case gol.StateChange:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+				paused = e.NewState == gol.Paused
+				state = e.String()
+				dirty = true
 				if e.NewState == gol.Quitting {
 					break sdl
 				}
@@ -75,16 +202,20 @@ sdl:
 	}
 }
 
-func RunHeadless(events <-chan gol.Event) {
-	avgTurns := util.NewAvgTurns()
+// RunHeadless prints the events of a run along with its throughput until the events channel is closed.
+func RunHeadless(p gol.Params, events <-chan gol.Event) {
+	throughput := util.NewThroughput(p.ImageWidth*p.ImageHeight, p.Turns)
 	for event := range events {
 		switch e := event.(type) {
 		case gol.AliveCellsCount:
-			fmt.Printf("Completed Turns %-8v %-20v Avg%+5v turns/sec\n", event.GetCompletedTurns(), event, avgTurns.Get(event.GetCompletedTurns()))
+			throughput.Update(event.GetCompletedTurns())
+			fmt.Printf("Completed Turns %-8v %-20v %v\n", event.GetCompletedTurns(), event, throughput)
 		case gol.FinalTurnComplete:
 			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), "Final Turn Complete")
 		case gol.ImageOutputComplete:
 			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+		case gol.ErrorOccurred:
+			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 		case gol.StateChange:
 			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			if e.NewState == gol.Quitting {
@@ -93,3 +224,37 @@ func RunHeadless(events <-chan gol.Event) {
 		}
 	}
 }
+
+// RunJSON writes every event to w in JSON Lines format until the events channel is closed.
+// The stream is flushed after every event other than cell flips, so it can be followed live.
+func RunJSON(events <-chan gol.Event, w io.Writer) error {
+	encoder := gol.NewEventEncoder(w)
+	for event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		switch event.(type) {
+		case gol.CellFlipped, gol.CellsFlipped:
+		default:
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return encoder.Flush()
+}
+
+// RunRecord writes every event to a binary recording, which can be played back with gol.Replay,
+// until the events channel is closed.
+func RunRecord(p gol.Params, events <-chan gol.Event, w io.Writer) error {
+	recorder, err := gol.NewRecorder(w, p.ImageWidth, p.ImageHeight)
+	if err != nil {
+		return err
+	}
+	for event := range events {
+		if err := recorder.Record(event); err != nil {
+			return err
+		}
+	}
+	return recorder.Flush()
+}
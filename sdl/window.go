@@ -2,46 +2,92 @@ package sdl
 
 import (
 	"fmt"
+	"math"
 	"unsafe"
 	
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
+// Zooming is limited to between minZoom and maxZoom screen pixels per cell.
+// Grid lines are drawn between cells when zoomed in to at least gridZoom.
+const (
+	minZoom  = 1.0 / 16
+	maxZoom  = 64
+	gridZoom = 8
+)
+
+// Window shows a world of Width x Height cells. Its pixel buffer holds one pixel per cell, which is
+// scaled and positioned in the window by the view: zoom is the number of screen pixels per cell and
+// (originX, originY) is where the top left corner of the world is drawn.
+// In colour modes other than Plain the frame is drawn from colours, which is filled in from the
+// pixels and the history of every cell.
 type Window struct {
-	Width, Height int32
-	window        *sdl.Window
-	renderer      *sdl.Renderer
-	texture       *sdl.Texture
-	pixels        []byte
+	Width, Height    int32
+	window           *sdl.Window
+	renderer         *sdl.Renderer
+	texture          *sdl.Texture
+	pixels           []byte
+	zoom             float64
+	originX, originY float64
+	grid             bool
+	mode             ColourMode
+	colours          []byte
+	history          *cellHistory
+	alive            int
+	hud              []string
 }
 
 func filterEvent(e sdl.Event, userdata interface{}) bool {
-	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
+	switch e.GetType() {
+	case sdl.KEYDOWN, sdl.QUIT, sdl.MOUSEBUTTONDOWN, sdl.MOUSEBUTTONUP, sdl.MOUSEMOTION, sdl.MOUSEWHEEL, sdl.WINDOWEVENT:
+		return true
+	}
+	return false
 }
 
+// NewWindow creates a resizable window showing a world of width x height cells, sized to fit the
+// world on the screen at a whole number zoom when it is small, or scaled down when it is large.
 func NewWindow(width, height int32) *Window {
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	util.Check(err)
-	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
+	windowWidth, windowHeight := windowSize(width, height)
+	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, windowWidth, windowHeight, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	util.Check(err)
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
 	util.Check(err)
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
-	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
+	// Cells stay sharp squares when zoomed in.
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest")
 	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
 	util.Check(err)
 
 	sdl.SetEventFilterFunc(filterEvent, nil)
-	return &Window{
-		width,
-		height,
-		window,
-		renderer,
-		texture,
-		make([]byte, width*height*4),
+	w := &Window{
+		Width:    width,
+		Height:   height,
+		window:   window,
+		renderer: renderer,
+		texture:  texture,
+		pixels:   make([]byte, width*height*4),
+		colours:  make([]byte, width*height*4),
+		history:  newCellHistory(int(width * height)),
 	}
+	w.FitToWindow()
+	return w
+}
+
+// windowSize picks the size of a new window for a world of width x height cells, fitting it within
+// most of the usable area of the screen.
+func windowSize(width, height int32) (int32, int32) {
+	maxWidth, maxHeight := int32(1024), int32(768)
+	if bounds, err := sdl.GetDisplayUsableBounds(0); err == nil {
+		maxWidth, maxHeight = bounds.W*3/4, bounds.H*3/4
+	}
+	zoom := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	if zoom >= 1 {
+		zoom = math.Min(math.Floor(zoom), maxZoom)
+	}
+	return int32(float64(width) * zoom), int32(float64(height) * zoom)
 }
 
 func (w *Window) Destroy() {
@@ -55,33 +101,198 @@ func (w *Window) Destroy() {
 }
 
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, unsafe.Pointer(&w.pixels[0]), int(w.Width*4))
+	frame := w.pixels
+	if w.mode != Plain {
+		w.history.recolour(w.mode, w.pixels, w.colours)
+		frame = w.colours
+	}
+	err := w.texture.Update(nil, unsafe.Pointer(&frame[0]), int(w.Width*4))
+	util.Check(err)
+	err = w.renderer.SetDrawColor(0, 0, 0, 0xFF)
 	util.Check(err)
 	err = w.renderer.Clear()
 	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
+	dst := sdl.Rect{
+		X: int32(math.Round(w.originX)),
+		Y: int32(math.Round(w.originY)),
+		W: int32(math.Round(float64(w.Width) * w.zoom)),
+		H: int32(math.Round(float64(w.Height) * w.zoom)),
+	}
+	err = w.renderer.Copy(w.texture, nil, &dst)
 	util.Check(err)
+	if w.grid && w.zoom >= gridZoom {
+		w.renderGrid(dst)
+	}
+	if len(w.hud) > 0 {
+		w.renderHUD()
+	}
 	w.renderer.Present()
 }
 
+// hudScale is the size in screen pixels of each pixel of the HUD's font, and hudMargin the space
+// around the HUD's text.
+const (
+	hudScale  = 2
+	hudMargin = 6
+)
+
+// renderHUD draws the lines of the HUD in the top left corner of the window over a translucent box.
+func (w *Window) renderHUD() {
+	lineHeight := int32(glyphHeight+3) * hudScale
+	longest := 0
+	var rects []sdl.Rect
+	for i, line := range w.hud {
+		if len(line) > longest {
+			longest = len(line)
+		}
+		rects = append(rects, textRects(line, hudMargin, hudMargin+int32(i)*lineHeight, hudScale)...)
+	}
+	background := sdl.Rect{
+		W: int32(longest)*(glyphWidth+1)*hudScale + 2*hudMargin,
+		H: int32(len(w.hud))*lineHeight + 2*hudMargin - 3*hudScale,
+	}
+
+	err := w.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	util.Check(err)
+	err = w.renderer.SetDrawColor(0, 0, 0, 0xA0)
+	util.Check(err)
+	err = w.renderer.FillRect(&background)
+	util.Check(err)
+	err = w.renderer.SetDrawColor(0xFF, 0xFF, 0xFF, 0xFF)
+	util.Check(err)
+	err = w.renderer.FillRects(rects)
+	util.Check(err)
+	err = w.renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	util.Check(err)
+}
+
+// SetHUD sets the lines of text shown over the world from the next frame, or hides it if there are none.
+func (w *Window) SetHUD(lines []string) {
+	w.hud = lines
+}
+
+// ZoomLevel returns the number of screen pixels each cell is drawn with.
+func (w *Window) ZoomLevel() float64 {
+	return w.zoom
+}
+
+// Alive returns the number of set pixels, i.e. alive cells.
+func (w *Window) Alive() int {
+	return w.alive
+}
+
+// renderGrid draws lines between the visible cells of the world drawn at dst.
+func (w *Window) renderGrid(dst sdl.Rect) {
+	windowWidth, windowHeight := w.window.GetSize()
+	err := w.renderer.SetDrawColor(0x40, 0x40, 0x40, 0xFF)
+	util.Check(err)
+	top, bottom := max32(dst.Y, 0), min32(dst.Y+dst.H, windowHeight)
+	left, right := max32(dst.X, 0), min32(dst.X+dst.W, windowWidth)
+	for x := int32(0); x <= w.Width; x++ {
+		if sx := int32(math.Round(w.originX + float64(x)*w.zoom)); sx >= left && sx <= right {
+			util.Check(w.renderer.DrawLine(sx, top, sx, bottom))
+		}
+	}
+	for y := int32(0); y <= w.Height; y++ {
+		if sy := int32(math.Round(w.originY + float64(y)*w.zoom)); sy >= top && sy <= bottom {
+			util.Check(w.renderer.DrawLine(left, sy, right, sy))
+		}
+	}
+}
+
+// Zoom multiplies the zoom by factor, keeping the cell under the screen position (x, y) in place.
+func (w *Window) Zoom(factor float64, x, y int32) {
+	zoom := math.Max(minZoom, math.Min(maxZoom, w.zoom*factor))
+	factor = zoom / w.zoom
+	w.originX = float64(x) - (float64(x)-w.originX)*factor
+	w.originY = float64(y) - (float64(y)-w.originY)*factor
+	w.zoom = zoom
+}
+
+// ZoomCentre zooms around the centre of the window.
+func (w *Window) ZoomCentre(factor float64) {
+	windowWidth, windowHeight := w.window.GetSize()
+	w.Zoom(factor, windowWidth/2, windowHeight/2)
+}
+
+// Pan moves the world by (dx, dy) screen pixels, keeping at least part of it in the window.
+func (w *Window) Pan(dx, dy int32) {
+	windowWidth, windowHeight := w.window.GetSize()
+	worldWidth, worldHeight := float64(w.Width)*w.zoom, float64(w.Height)*w.zoom
+	w.originX = math.Max(-worldWidth+1, math.Min(float64(windowWidth)-1, w.originX+float64(dx)))
+	w.originY = math.Max(-worldHeight+1, math.Min(float64(windowHeight)-1, w.originY+float64(dy)))
+}
+
+// FitToWindow zooms so that the whole world fits the window and centres it.
+func (w *Window) FitToWindow() {
+	windowWidth, windowHeight := w.window.GetSize()
+	w.zoom = math.Min(float64(windowWidth)/float64(w.Width), float64(windowHeight)/float64(w.Height))
+	w.zoom = math.Max(minZoom, math.Min(maxZoom, w.zoom))
+	w.originX = (float64(windowWidth) - float64(w.Width)*w.zoom) / 2
+	w.originY = (float64(windowHeight) - float64(w.Height)*w.zoom) / 2
+}
+
+// ToggleGrid shows or hides the grid lines drawn between cells at high zoom.
+func (w *Window) ToggleGrid() {
+	w.grid = !w.grid
+}
+
+// CellAt returns the cell shown at the screen position (x, y), if there is one.
+func (w *Window) CellAt(x, y int32) (util.Cell, bool) {
+	cellX := int(math.Floor((float64(x) - w.originX) / w.zoom))
+	cellY := int(math.Floor((float64(y) - w.originY) / w.zoom))
+	if cellX < 0 || cellY < 0 || cellX >= int(w.Width) || cellY >= int(w.Height) {
+		return util.Cell{}, false
+	}
+	return util.Cell{X: cellX, Y: cellY}, true
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func (w *Window) PollEvent() sdl.Event {
 	return sdl.PollEvent()
 }
 
 func (w *Window) SetPixel(x, y int) {
 	width := int(w.Width)
+	if !w.PixelSet(x, y) {
+		w.alive++
+	}
 	w.pixels[4*(y*width+x)+0] = 0xFF
 	w.pixels[4*(y*width+x)+1] = 0xFF
 	w.pixels[4*(y*width+x)+2] = 0xFF
 	w.pixels[4*(y*width+x)+3] = 0xFF
 }
 
+// PixelSet reports whether the pixel at (x, y) is set, i.e. the cell there is alive.
+func (w *Window) PixelSet(x, y int) bool {
+	return w.pixels[4*(y*int(w.Width)+x)] == 0xFF
+}
+
 func (w *Window) FlipPixel(x, y int) {
 	if x < 0 || y < 0 || x >= int(w.Width) || y >= int(w.Height) {
 		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the window.", x, y))
 	}
 
 	width := int(w.Width)
+	w.history.flip(y*width + x)
+	if w.PixelSet(x, y) {
+		w.alive--
+	} else {
+		w.alive++
+	}
 	w.pixels[4*(y*width+x)+0] = ^w.pixels[4*(y*width+x)+0]
 	w.pixels[4*(y*width+x)+1] = ^w.pixels[4*(y*width+x)+1]
 	w.pixels[4*(y*width+x)+2] = ^w.pixels[4*(y*width+x)+2]
@@ -102,4 +313,18 @@ func (w *Window) ClearPixels() {
 	for i := range w.pixels {
 		w.pixels[i] = 0
 	}
+	w.history = newCellHistory(int(w.Width * w.Height))
+	w.alive = 0
+}
+
+// SetTurn tells the window the turn that following flips happen in, which the colour modes use to
+// work out the age of cells.
+func (w *Window) SetTurn(turn int) {
+	w.history.turn = turn
+}
+
+// CycleColourMode switches to the next colour mode and returns it.
+func (w *Window) CycleColourMode() ColourMode {
+	w.mode = w.mode.Next()
+	return w.mode
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestSoup tests that random soups are reproducible from their seed, have the requested symmetry and density,
+// and that the seed is recorded in the output image.
+func TestSoup(t *testing.T) {
+	density := 0.3
+	for _, symmetry := range []string{gol.C1, gol.C2, gol.C4, gol.D8} {
+		t.Run(symmetry, func(t *testing.T) {
+			p := gol.Params{
+				Turns:       0,
+				Threads:     1,
+				ImageWidth:  64,
+				ImageHeight: 64,
+				Soup:        &gol.Soup{Density: &density, Seed: 42, Symmetry: symmetry},
+			}
+			emptyOutFolder()
+			first := runSoup(t, p)
+			second := runSoup(t, p)
+			assertEqualBoard(t, first, second, p)
+
+			density := float64(len(first)) / float64(p.ImageWidth*p.ImageHeight)
+			assert(t, density > 0.2 && density < 0.4, "Expected a density of about 0.3, got %v", density)
+
+			alive := make(map[util.Cell]bool)
+			for _, cell := range first {
+				alive[cell] = true
+			}
+			w, h := p.ImageWidth-1, p.ImageHeight-1
+			for _, cell := range first {
+				switch symmetry {
+				case gol.C2:
+					assert(t, alive[util.Cell{X: w - cell.X, Y: h - cell.Y}], "%v is not C2 symmetric", cell)
+				case gol.C4:
+					assert(t, alive[util.Cell{X: w - cell.Y, Y: cell.X}], "%v is not C4 symmetric", cell)
+				case gol.D8:
+					assert(t, alive[util.Cell{X: cell.Y, Y: cell.X}], "%v is not D8 symmetric", cell)
+					assert(t, alive[util.Cell{X: w - cell.X, Y: cell.Y}], "%v is not D8 symmetric", cell)
+				}
+			}
+
+			path := fmt.Sprintf("out/%vx%vx0.pgm", p.ImageWidth, p.ImageHeight)
+			data, err := os.ReadFile(path)
+			util.Check(err)
+			// The seed is recorded in a comment after the magic number, which readAliveCells doesn't expect.
+			lines := strings.SplitN(string(data), "\n", 3)
+			assert(t, len(lines) == 3 && strings.HasPrefix(lines[1], "# random soup seed 42 "), "The seed is not recorded in %v", path)
+			uncommented := filepath.Join(t.TempDir(), "soup.pgm")
+			util.Check(os.WriteFile(uncommented, []byte(lines[0]+"\n"+lines[2]), 0644))
+			assertEqualBoard(t, readAliveCells(uncommented, p.ImageWidth, p.ImageHeight), first, p)
+		})
+	}
+
+	t.Run("seeds", func(t *testing.T) {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Soup: &gol.Soup{Seed: 1}}
+		first := runSoup(t, p)
+		p.Soup = &gol.Soup{Seed: 2}
+		second := runSoup(t, p)
+		assert(t, !checkEqualBoard(first, second), "Different seeds generated the same soup")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		empty := 0.0
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Soup: &gol.Soup{Density: &empty, Seed: 0}}
+		cells := runSoup(t, p)
+		assert(t, len(cells) == 0, "Expected a density of 0 to give an empty world, got %v alive cells", len(cells))
+	})
+
+	t.Run("square", func(t *testing.T) {
+		testIoError(t, gol.Params{ImageWidth: 16, ImageHeight: 64, Soup: &gol.Soup{Symmetry: gol.C4}})
+	})
+}
+
+// TestSoupHeader tests that output images only record the soup the world came from, which is the
+// new seed after the world is randomised, and that nothing is recorded once it is cleared or edited.
+func TestSoupHeader(t *testing.T) {
+	p := gol.Params{Turns: 100000000, Threads: 2, ImageWidth: 16, ImageHeight: 16, OutDir: t.TempDir(), Soup: &gol.Soup{Seed: 42}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune, 10)
+	edits := make(chan gol.Edit, 10)
+	go gol.RunContext(ctx, p, gol.WithEvents(events), gol.WithKeyPresses(keyPresses), gol.WithEdits(edits), gol.WithProgress(io.Discard))
+
+	// header saves the world and returns the comment in the header of the image, if there is one.
+	header := func() string {
+		keyPresses <- 's'
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					t.Fatal("The events channel was closed early")
+				}
+				if e, ok := event.(gol.ImageOutputComplete); ok {
+					data, err := os.ReadFile(filepath.Join(p.OutDir, e.Filename+".pgm"))
+					util.Check(err)
+					if lines := strings.SplitN(string(data), "\n", 3); strings.HasPrefix(lines[1], "# ") {
+						return lines[1]
+					}
+					return ""
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("No image was output in 2 seconds")
+			}
+		}
+	}
+
+	keyPresses <- 'p'
+	comment := header()
+	assert(t, strings.HasPrefix(comment, "# random soup seed 42 "), "Expected the seed of the initial soup to be recorded, got %q", comment)
+	keyPresses <- 'r'
+	comment = header()
+	assert(t, strings.HasPrefix(comment, "# random soup seed ") && !strings.HasPrefix(comment, "# random soup seed 42 "),
+		"Expected the new seed to be recorded after randomising the world, got %q", comment)
+	edits <- gol.Edit{Cell: util.Cell{X: 1, Y: 1}, Alive: true}
+	edits <- gol.Edit{Cell: util.Cell{X: 1, Y: 1}, Alive: false}
+	// Edits and key presses arrive on different channels, so wait for the edit before saving.
+	for {
+		if _, ok := (<-events).(gol.CellsFlipped); ok {
+			break
+		}
+	}
+	comment = header()
+	assert(t, comment == "", "Expected no soup to be recorded after editing the world, got %q", comment)
+	keyPresses <- 'r'
+	keyPresses <- 'c'
+	comment = header()
+	assert(t, comment == "", "Expected no soup to be recorded after clearing the world, got %q", comment)
+}
+
+func runSoup(t *testing.T, p gol.Params) []util.Cell {
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		case gol.ErrorOccurred:
+			t.Fatal(e)
+		}
+	}
+	return cells
+}
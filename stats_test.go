@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestStats tests the population statistics of a small world with shapes that wrap around its edges.
+func TestStats(t *testing.T) {
+	width, height := 8, 8
+	world := make([][]byte, height)
+	for i := range world {
+		world[i] = make([]byte, width)
+	}
+	alive := []util.Cell{
+		// A blinker in the top left region.
+		{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1},
+		// A domino wrapping around the left and right edges.
+		{X: 7, Y: 5}, {X: 0, Y: 5},
+		// A single cell touching the domino diagonally across the edge.
+		{X: 1, Y: 6},
+		// An isolated cell in the bottom right region.
+		{X: 5, Y: 4},
+	}
+	for _, cell := range alive {
+		world[cell.Y][cell.X] = 255
+	}
+
+	stats := util.CalculateStats(world, width, height, 2)
+	assert(t, stats.Alive == len(alive), "Expected %v alive cells, got %v", len(alive), stats.Alive)
+	assert(t, stats.Components == 3, "Expected 3 connected components, got %v", stats.Components)
+	assert(t, stats.Min == util.Cell{X: 0, Y: 1} && stats.Max == util.Cell{X: 7, Y: 6},
+		"Expected a bounding box from (0, 1) to (7, 6), got %v to %v", stats.Min, stats.Max)
+
+	expected := [][]float64{{3.0 / 16, 0}, {2.0 / 16, 2.0 / 16}}
+	for j := range expected {
+		for i := range expected[j] {
+			assert(t, stats.Density[j][i] == expected[j][i],
+				"Expected a density of %v in region (%v, %v), got %v", expected[j][i], i, j, stats.Density[j][i])
+		}
+	}
+
+	empty := util.CalculateStats(make([][]byte, 0), 0, 0, 2)
+	assert(t, empty.Alive == 0 && empty.Components == 0 && empty.Min == util.Cell{} && empty.Max == util.Cell{},
+		"Expected an empty world to have no population, got %+v", empty)
+}
+
+// TestStatsTracker tests that stats kept up to date from flipped cells, with components counted in
+// parallel, match those measured from scratch.
+func TestStatsTracker(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	width, height := 37, 23
+	world := randomWorld(rng, width, height, 0.3)
+	tracker := util.NewStatsTracker(world, width, height, 3)
+	for turn := 0; turn < 20; turn++ {
+		for i := 0; i < 50; i++ {
+			cell := util.Cell{X: rng.Intn(width), Y: rng.Intn(height)}
+			world[cell.Y][cell.X] = ^world[cell.Y][cell.X]
+			tracker.Flip(cell, world[cell.Y][cell.X] == 255)
+		}
+		expected := util.CalculateStats(world, width, height, 3)
+		for _, threads := range []int{1, 2, 5, 64} {
+			stats := tracker.Stats(world, threads)
+			assert(t, reflect.DeepEqual(stats, expected), "Turn %v with %v threads expected %+v, got %+v", turn, threads, expected, stats)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestStep tests stepping single turns and a typed number of turns while paused.
+func TestStep(t *testing.T) {
+	p := gol.Params{Turns: 100000000, Threads: 2, ImageWidth: 16, ImageHeight: 16}
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+	go gol.Run(p, events, keyPresses)
+
+	keyPresses <- 'p'
+	state := awaitState(t, events)
+	for state.NewState != gol.Paused {
+		state = awaitState(t, events)
+	}
+
+	for _, step := range []struct {
+		keys  string
+		turns int
+	}{{"n", 1}, {"12n", 12}, {"n", 1}} {
+		for _, key := range step.keys {
+			keyPresses <- key
+		}
+		turns := 0
+		state := awaitState(t, events)
+		assert(t, state.NewState == gol.Executing, "Expected a StateChange to Executing when stepping, got %v", state)
+		for {
+			event := awaitEvent(t, events)
+			if _, ok := event.(gol.TurnComplete); ok {
+				turns++
+			} else if e, ok := event.(gol.StateChange); ok {
+				assert(t, e.NewState == gol.Paused, "Expected a StateChange to Paused after stepping, got %v", e)
+				break
+			}
+		}
+		assert(t, turns == step.turns, "Pressing %v stepped %v turns instead of %v", step.keys, turns, step.turns)
+	}
+
+	keyPresses <- 'q'
+	for range events {
+	}
+}
+
+// TestThrottle tests that turns are limited to the rate given and that it can be halved and doubled.
+func TestThrottle(t *testing.T) {
+	p := gol.Params{Turns: 100000000, Threads: 2, ImageWidth: 16, ImageHeight: 16, TurnsPerSecond: 20}
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+	go gol.Run(p, events, keyPresses)
+
+	state := awaitState(t, events)
+	assert(t, state.TurnsPerSecond == 20, "Expected a rate of 20 turns/s, got %v", state)
+	turns := countTurns(events, time.Second)
+	assert(t, turns >= 10 && turns <= 30, "Expected about 20 turns in a second at 20 turns/s, got %v", turns)
+
+	keyPresses <- ']'
+	for state.TurnsPerSecond == 20 {
+		state = awaitState(t, events)
+	}
+	assert(t, state.TurnsPerSecond == 40 && state.NewState == gol.Executing, "Expected to execute at 40 turns/s, got %v", state)
+	keyPresses <- '['
+	keyPresses <- '['
+	for state.TurnsPerSecond != 10 {
+		state = awaitState(t, events)
+	}
+	turns = countTurns(events, time.Second)
+	assert(t, turns >= 5 && turns <= 15, "Expected about 10 turns in a second at 10 turns/s, got %v", turns)
+
+	keyPresses <- 'q'
+	for range events {
+	}
+}
+
+// awaitEvent returns the next event, failing if none is sent in 2 seconds.
+func awaitEvent(t *testing.T, events <-chan gol.Event) gol.Event {
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("The events channel was closed early")
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("No event was sent in 2 seconds")
+	}
+	return nil
+}
+
+// awaitState returns the next StateChange.
+func awaitState(t *testing.T, events <-chan gol.Event) gol.StateChange {
+	for {
+		if e, ok := awaitEvent(t, events).(gol.StateChange); ok {
+			return e
+		}
+	}
+}
+
+// countTurns counts the TurnComplete events sent in the given time.
+func countTurns(events <-chan gol.Event, d time.Duration) int {
+	turns := 0
+	timeout := time.After(d)
+	for {
+		select {
+		case event := <-events:
+			if _, ok := event.(gol.TurnComplete); ok {
+				turns++
+			}
+		case <-timeout:
+			return turns
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package stubs
 
+import "uk.ac.bris.cs/gameoflife/util"
+
 var ProcessGameOfLife = "GolOperations.ProcessAllTurns"
-var Reporter = "GolOperations.CalculateAliveCells"
+var Shutdown = "GolOperations.Shutdown"
 
 type Response struct {
 	Grid  [][]byte
@@ -13,4 +15,5 @@ type Request struct {
 	Width  int
 	Height int
 	Turns  int
+	Rule   util.Rule
 }
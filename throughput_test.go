@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestThroughput tests that the throughput estimate handles sub-second intervals and rates below
+// 1 turn/sec, smooths changes in rate and estimates the time left.
+func TestThroughput(t *testing.T) {
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-6*math.Max(1, math.Abs(b))
+	}
+
+	throughput := util.NewThroughput(100, 1000)
+	start := time.Now()
+	throughput.Start(0, start)
+	_, ok := throughput.ETA()
+	assert(t, !ok, "Expected no ETA before the first update")
+
+	rate := throughput.UpdateAt(50, start.Add(250*time.Millisecond))
+	assert(t, near(rate, 200), "Expected 200 turns/sec after 50 turns in 250ms, got %v", rate)
+	assert(t, near(throughput.CellsPerSecond(), 20000), "Expected 20000 cells/sec, got %v", throughput.CellsPerSecond())
+	eta, ok := throughput.ETA()
+	assert(t, ok && eta == 5*time.Second, "Expected 950 turns to take 5s at 200 turns/sec, got %v", eta)
+
+	// A sudden change is only partly taken into account.
+	rate = throughput.UpdateAt(60, start.Add(1250*time.Millisecond))
+	weight := 1 - math.Exp(-1/util.ThroughputWindow.Seconds())
+	assert(t, near(rate, 200+weight*(10-200)), "Expected the rate to move part of the way to 10 turns/sec, got %v", rate)
+	for i := 1; i <= 60; i++ {
+		rate = throughput.UpdateAt(60+10*i, start.Add(1250*time.Millisecond+time.Duration(i)*time.Second))
+	}
+	assert(t, math.Abs(rate-10) < 0.01, "Expected the rate to settle at 10 turns/sec, got %v", rate)
+
+	slow := util.NewThroughput(1, 0)
+	slow.Start(0, start)
+	rate = slow.UpdateAt(1, start.Add(4*time.Second))
+	assert(t, near(rate, 0.25), "Expected 0.25 turns/sec, got %v", rate)
+	_, ok = slow.ETA()
+	assert(t, !ok, "Expected no ETA without a number of turns")
+
+	for v, expected := range map[float64]string{0: "0", 999: "999", 1234: "1.23k", 45600000: "45.6M", 999999: "1M"} {
+		assert(t, util.FormatSI(v) == expected, "Expected %v to be formatted as %v, got %v", v, expected, util.FormatSI(v))
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"runtime/trace"
 	"testing"
 	"uk.ac.bris.cs/gameoflife/gol"
@@ -16,9 +17,10 @@ func TestTrace(t *testing.T) {
 		ImageWidth:  64,
 		ImageHeight: 64,
 	}
-	f, _ := os.Create("trace.out")
+	f, err := os.Create(filepath.Join(t.TempDir(), "trace.out"))
+	util.Check(err)
 	events := make(chan gol.Event)
-	err := trace.Start(f)
+	err = trace.Start(f)
 	util.Check(err)
 	go gol.Run(traceParams, events, nil)
 	for range events {
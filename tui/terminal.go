@@ -0,0 +1,153 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ANSI escape sequences used to take over the terminal.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	home        = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearBelow  = "\x1b[J"
+)
+
+// The arrow keys are read as these runes, from the Unicode private use area.
+const (
+	keyUp rune = 0xF700 + iota
+	keyDown
+	keyLeft
+	keyRight
+)
+
+// stty runs stty on the terminal connected to stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// isTerminal returns whether file is a terminal rather than a pipe or a regular file.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// makeCbreak switches the terminal to cbreak mode, in which key presses are read as soon as they are
+// typed without being echoed, and returns a function that restores the previous mode.
+// Signals such as Ctrl-C still work as usual. It fails if stdin isn't a terminal or there is no stty,
+// as on Windows. Reads time out after a tenth of a second so that reading keys can be stopped.
+func makeCbreak() (restore func(), err error) {
+	if !isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("stdin is not a terminal")
+	}
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the terminal mode: %w", err)
+	}
+	if _, err = stty("-icanon", "-echo", "min", "0", "time", "1"); err != nil {
+		return nil, err
+	}
+	return func() { _, _ = stty(state) }, nil
+}
+
+// terminalReader reads keys from a terminal in cbreak mode, waiting through the reads that time out
+// without a key until it is stopped.
+type terminalReader struct {
+	file    *os.File
+	mutex   sync.Mutex
+	stopped bool
+}
+
+// Read reads the next keys typed, or returns io.EOF once the reader has been stopped.
+func (r *terminalReader) Read(b []byte) (int, error) {
+	for {
+		r.mutex.Lock()
+		if r.stopped {
+			r.mutex.Unlock()
+			return 0, io.EOF
+		}
+		n, err := r.file.Read(b)
+		r.mutex.Unlock()
+		// A read that times out without a key reads nothing, which is reported as the end of the file.
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+	}
+}
+
+// stop makes every later read return io.EOF, waiting for a read in progress to time out so that
+// the terminal mode can be restored once it returns.
+func (r *terminalReader) stop() {
+	r.mutex.Lock()
+	r.stopped = true
+	r.mutex.Unlock()
+}
+
+// terminalSize returns the number of columns and rows of the terminal, or 80x24 if it is unknown.
+func terminalSize() (cols, rows int) {
+	out, err := stty("size")
+	if err == nil {
+		if _, err = fmt.Sscan(out, &rows, &cols); err == nil && cols > 0 && rows > 0 {
+			return cols, rows
+		}
+	}
+	return 80, 24
+}
+
+// readKeys sends every key read from input to keys, translating the escape sequences of the arrow
+// keys, until input ends or done is closed. Escape on its own is read as 'q'.
+func readKeys(input io.Reader, keys chan<- rune, done <-chan struct{}) {
+	defer close(keys)
+	send := func(key rune) bool {
+		select {
+		case keys <- key:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	reader := bufio.NewReader(input)
+	for {
+		key, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+		if key == 0x1b {
+			// An escape sequence arrives all at once, so anything else already buffered is part of it.
+			if reader.Buffered() < 2 {
+				if !send('q') {
+					return
+				}
+				continue
+			}
+			sequence := make([]byte, 2)
+			if _, err = io.ReadFull(reader, sequence); err != nil {
+				return
+			}
+			switch string(sequence) {
+			case "[A":
+				key = keyUp
+			case "[B":
+				key = keyDown
+			case "[C":
+				key = keyRight
+			case "[D":
+				key = keyLeft
+			default:
+				continue
+			}
+		}
+		if !send(key) {
+			return
+		}
+	}
+}
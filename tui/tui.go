@@ -0,0 +1,268 @@
+// Package tui shows the Game of Life live in a terminal, for watching runs without a display.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// FPS is the most frames per second drawn.
+const FPS = 20
+
+// Mode decides how cells are packed into characters.
+type Mode int
+
+const (
+	// Braille draws 2x4 cells per character with the dots of Unicode braille patterns.
+	Braille Mode = iota
+	// HalfBlocks draws 1x2 cells per character with Unicode half block characters.
+	HalfBlocks
+)
+
+func (mode Mode) String() string {
+	if mode == Braille {
+		return "Braille"
+	}
+	return "Half blocks"
+}
+
+// cellsPerChar returns the number of cells across and down each character.
+func (mode Mode) cellsPerChar() (int, int) {
+	if mode == Braille {
+		return 2, 4
+	}
+	return 1, 2
+}
+
+// brailleDots[y][x] is the dot of a braille pattern for the cell at (x, y) within a character.
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// forwarded are the keys passed on to the engine, which are the same as those of the SDL window.
+const forwarded = "psqkcrnb[]0123456789"
+
+// view is the state of the terminal viewer. The cell at (x, y) is drawn in the top left corner.
+type view struct {
+	world         [][]bool
+	width, height int
+	cols, rows    int
+	x, y          int
+	mode          Mode
+	turn, alive   int
+	state, rule   string
+}
+
+func (v *view) flip(x, y int) {
+	v.world[y][x] = !v.world[y][x]
+	if v.world[y][x] {
+		v.alive++
+	} else {
+		v.alive--
+	}
+}
+
+func (v *view) cell(x, y int) bool {
+	return x < v.width && y < v.height && v.world[y][x]
+}
+
+func (v *view) char(x, y int) rune {
+	if v.mode == Braille {
+		pattern := rune(0)
+		for dy := range brailleDots {
+			for dx := range brailleDots[dy] {
+				if v.cell(x+dx, y+dy) {
+					pattern |= brailleDots[dy][dx]
+				}
+			}
+		}
+		if pattern == 0 {
+			return ' '
+		}
+		return 0x2800 + pattern
+	}
+	switch top, bottom := v.cell(x, y), v.cell(x, y+1); {
+	case top && bottom:
+		return '█'
+	case top:
+		return '▀'
+	case bottom:
+		return '▄'
+	default:
+		return ' '
+	}
+}
+
+// pan moves the view by (dx, dy) characters, keeping it within the world.
+func (v *view) pan(dx, dy int) {
+	cw, ch := v.mode.cellsPerChar()
+	v.x = clamp(v.x+dx*cw, 0, v.width-v.cols*cw)
+	v.y = clamp(v.y+dy*ch, 0, v.height-(v.rows-1)*ch)
+}
+
+func clamp(n, low, high int) int {
+	if n > high {
+		n = high
+	}
+	if n < low {
+		n = low
+	}
+	return n
+}
+
+// render draws a whole frame, with the world above a status line.
+func (v *view) render(b *strings.Builder) {
+	cw, ch := v.mode.cellsPerChar()
+	b.WriteString(home)
+	for row := 0; row < v.rows-1; row++ {
+		for col := 0; col < v.cols; col++ {
+			x, y := v.x+col*cw, v.y+row*ch
+			if x >= v.width || y >= v.height {
+				break
+			}
+			b.WriteRune(v.char(x, y))
+		}
+		b.WriteString(clearLine + "\r\n")
+	}
+	status := fmt.Sprintf("Turn %v  Alive %v  %v  Rule %v  %v at (%v, %v)", v.turn, v.alive, v.state, v.rule, v.mode, v.x, v.y)
+	if len(status) > v.cols {
+		status = status[:v.cols]
+	}
+	b.WriteString(status + clearLine + clearBelow)
+}
+
+// Run shows the world in the terminal until a StateChange to Quitting or the events channel is closed.
+// Keys are read from stdin and forwarded to the engine as in the SDL window, except for the arrow
+// keys, which pan when the world doesn't fit, and m, which switches between braille and half blocks.
+// If stdin can't be switched to cbreak mode, such as when it isn't a terminal, the world is still
+// drawn but no keys are read. It fails if stdout isn't a terminal. Nothing else should write to
+// stdout while it runs, so runs should be started with gol.WithProgress; images written are shown
+// in the status line instead.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) error {
+	if !isTerminal(os.Stdout) {
+		return errors.New("stdout is not a terminal")
+	}
+	var input io.Reader
+	if restore, err := makeCbreak(); err == nil {
+		defer restore()
+		reader := &terminalReader{file: os.Stdin}
+		defer reader.stop()
+		input = reader
+	}
+	fmt.Fprint(os.Stdout, enterScreen)
+	defer fmt.Fprint(os.Stdout, leaveScreen)
+	View(p, events, keyPresses, input, os.Stdout, terminalSize)
+	return nil
+}
+
+// View draws the world to out as Run does, reading keys from input unless it is nil. size returns
+// the number of columns and rows there are to draw in and is checked every second. Keys stop being
+// read when it returns, once the read in progress returns.
+func View(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, input io.Reader, out io.Writer, size func() (cols, rows int)) {
+	v := &view{
+		world:  make([][]bool, p.ImageHeight),
+		width:  p.ImageWidth,
+		height: p.ImageHeight,
+		state:  "Starting",
+		rule:   p.Rule,
+	}
+	for y := range v.world {
+		v.world[y] = make([]bool, p.ImageWidth)
+	}
+	if v.rule == "" {
+		v.rule = gol.DefaultRule
+	}
+	v.cols, v.rows = size()
+
+	var keys chan rune
+	if input != nil {
+		keys = make(chan rune, 10)
+		done := make(chan struct{})
+		defer close(done)
+		go readKeys(input, keys, done)
+	}
+	frames := time.NewTicker(time.Second / FPS)
+	defer frames.Stop()
+	resize := time.NewTicker(time.Second)
+	defer resize.Stop()
+	dirty := true
+	paused := false
+
+	draw := func() {
+		var b strings.Builder
+		v.render(&b)
+		_, _ = io.WriteString(out, b.String())
+		dirty = false
+	}
+
+	for {
+		select {
+		case <-frames.C:
+			if dirty {
+				draw()
+			}
+		case <-resize.C:
+			if cols, rows := size(); cols != v.cols || rows != v.rows {
+				v.cols, v.rows = cols, rows
+				v.pan(0, 0)
+				dirty = true
+			}
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			switch {
+			case key == keyUp:
+				v.pan(0, -v.rows/4)
+			case key == keyDown:
+				v.pan(0, v.rows/4)
+			case key == keyLeft:
+				v.pan(-v.cols/4, 0)
+			case key == keyRight:
+				v.pan(v.cols/4, 0)
+			case key == 'm':
+				v.mode = (v.mode + 1) % 2
+				v.pan(0, 0)
+			case strings.ContainsRune(forwarded, key):
+				keyPresses <- key
+			}
+			dirty = true
+		case event, ok := <-events:
+			if !ok {
+				draw()
+				return
+			}
+			switch e := event.(type) {
+			case gol.CellFlipped:
+				v.flip(e.Cell.X, e.Cell.Y)
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					v.flip(cell.X, cell.Y)
+				}
+				// Edits are made between turns, so show them straight away rather than at the next TurnComplete.
+				if paused {
+					dirty = true
+				}
+			case gol.TurnComplete:
+				v.turn = e.CompletedTurns
+				dirty = true
+			case gol.ErrorOccurred, gol.ImageOutputComplete:
+				v.state = e.String()
+				dirty = true
+			case gol.StateChange:
+				paused = e.NewState == gol.Paused
+				v.state = e.String()
+				dirty = true
+				if e.NewState == gol.Quitting {
+					draw()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/tui"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestTUI tests that the terminal viewer draws a blinker in braille and forwards key presses.
+func TestTUI(t *testing.T) {
+	p := gol.Params{ImageWidth: 8, ImageHeight: 8}
+	events := make(chan gol.Event, 10)
+	keyPresses := make(chan rune, 10)
+	var out bytes.Buffer
+	done := make(chan bool)
+	go func() {
+		tui.View(p, events, keyPresses, strings.NewReader("p\x1b[Bx"), &out, func() (int, int) { return 80, 5 })
+		done <- true
+	}()
+
+	select {
+	case key := <-keyPresses:
+		assert(t, key == 'p', "Expected p to be forwarded, got %q", key)
+	case <-time.After(2 * time.Second):
+		t.Fatal("No key was forwarded in 2 seconds")
+	}
+
+	events <- gol.CellsFlipped{CompletedTurns: 0, Cells: []util.Cell{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}}
+	events <- gol.StateChange{CompletedTurns: 0, NewState: gol.Executing}
+	events <- gol.TurnComplete{CompletedTurns: 1}
+	events <- gol.StateChange{CompletedTurns: 1, NewState: gol.Quitting}
+	<-done
+
+	// The vertical blinker fills the right hand column of dots in the first three rows of the first character.
+	frame := out.String()
+	frame = frame[strings.LastIndex(frame, "\x1b[H"):]
+	assert(t, strings.ContainsRune(frame, 0x2800+0x08+0x10+0x20), "The blinker is not drawn in braille:\n%q", frame)
+	assert(t, strings.Contains(frame, "Turn 1") && strings.Contains(frame, "Quitting"), "The status line is missing:\n%q", frame)
+	assert(t, len(keyPresses) == 0, "Only p should have been forwarded, got %v more", len(keyPresses))
+}
+
+// TestTUIStopsReadingKeys tests that the terminal viewer stops reading keys once it returns, and that
+// it draws without reading keys at all when there is no input.
+func TestTUIStopsReadingKeys(t *testing.T) {
+	before := runtime.NumGoroutine()
+	p := gol.Params{ImageWidth: 8, ImageHeight: 8}
+	size := func() (int, int) { return 80, 5 }
+	input, typing := io.Pipe()
+	events := make(chan gol.Event)
+	close(events)
+	tui.View(p, events, make(chan rune), input, io.Discard, size)
+
+	// Keys typed after it has returned are read once more, but not sent anywhere.
+	timeout(t, 2*time.Second, func() {
+		_, _ = typing.Write([]byte(strings.Repeat("m", 20)))
+	}, "The keys typed after the viewer returned were never read")
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert(t, runtime.NumGoroutine() <= before, "The key reader is still running after the viewer returned")
+
+	var out bytes.Buffer
+	events = make(chan gol.Event, 1)
+	events <- gol.TurnComplete{CompletedTurns: 3}
+	close(events)
+	tui.View(p, events, make(chan rune), nil, &out, size)
+	assert(t, strings.Contains(out.String(), "Turn 3"), "Nothing was drawn without input:\n%q", out.String())
+}
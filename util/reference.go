@@ -0,0 +1,27 @@
+package util
+
+// ReferenceStep returns world after one turn under rule. It's the Game of Life written as plainly
+// as possible, counting the neighbours of every cell with the edges wrapping around and looking the
+// result up in the rule, so that faster engines and the fixtures in check/ can be trusted against it.
+func ReferenceStep(world [][]byte, rule Rule) [][]byte {
+	height, width := len(world), len(world[0])
+	next := make([][]byte, height)
+	for y := range next {
+		next[y] = make([]byte, width)
+		for x := range next[y] {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && world[(y+dy+height)%height][(x+dx+width)%width] == 255 {
+						neighbours++
+					}
+				}
+			}
+			alive := world[y][x] == 255
+			if alive && rule.Survive[neighbours] || !alive && rule.Birth[neighbours] {
+				next[y][x] = 255
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule is a life-like cellular automaton rule. A dead cell with n alive neighbours is born if Birth[n]
+// is set and an alive cell with n alive neighbours survives if Survive[n] is set.
+type Rule struct {
+	Birth   [9]bool
+	Survive [9]bool
+}
+
+// Conway is the rule of Conway's Game of Life, B3/S23.
+var Conway = Rule{
+	Birth:   [9]bool{3: true},
+	Survive: [9]bool{2: true, 3: true},
+}
+
+// ParseRule parses a rule in B/S notation such as "B3/S23" or "B36/S23". The letters are case-insensitive
+// and the two halves may be given in either order.
+func ParseRule(s string) (Rule, error) {
+	var rule Rule
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(s)), "/")
+	if len(parts) != 2 {
+		return rule, fmt.Errorf("invalid rule %q: expected the form B3/S23", s)
+	}
+
+	seen := map[byte]bool{}
+	for _, part := range parts {
+		if len(part) == 0 || (part[0] != 'B' && part[0] != 'S') || seen[part[0]] {
+			return rule, fmt.Errorf("invalid rule %q: expected the form B3/S23", s)
+		}
+		seen[part[0]] = true
+
+		counts := &rule.Birth
+		if part[0] == 'S' {
+			counts = &rule.Survive
+		}
+		for _, digit := range part[1:] {
+			if digit < '0' || digit > '8' {
+				return rule, fmt.Errorf("invalid rule %q: %q is not a neighbour count", s, digit)
+			}
+			counts[digit-'0'] = true
+		}
+	}
+	return rule, nil
+}
+
+// Next returns whether a cell is alive in the next turn given whether it is alive now and its number
+// of alive neighbours.
+func (rule Rule) Next(alive bool, neighbours int) bool {
+	if alive {
+		return rule.Survive[neighbours]
+	}
+	return rule.Birth[neighbours]
+}
+
+// String returns the rule in B/S notation, e.g. "B3/S23".
+func (rule Rule) String() string {
+	var b strings.Builder
+	b.WriteByte('B')
+	for n, born := range rule.Birth {
+		if born {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	b.WriteString("/S")
+	for n, survives := range rule.Survive {
+		if survives {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package util
+
+import "sync"
+
+// DefaultRegions is the number of regions along each side of the world that Stats.Density is measured over.
+const DefaultRegions = 4
+
+// Stats summarises the population of a world.
+type Stats struct {
+	Alive int
+	// Births and Deaths count the cells that became alive or died in the last turn.
+	Births, Deaths int
+	// Min and Max are opposite corners of the smallest rectangle containing every alive cell.
+	// Both are zero when no cells are alive. Unlike Components, the rectangle doesn't wrap around the
+	// edges of the world, so a shape lying across an edge stretches it to the full width or height.
+	Min, Max Cell
+	// Density[j][i] is the fraction of alive cells in the region in row j and column i of an even grid over the world.
+	Density [][]float64
+	// Components is the number of groups of alive cells connected horizontally, vertically or diagonally,
+	// including across the edges of the world.
+	Components int
+}
+
+// CalculateStats measures a world of 0 and 255 valued cells, dividing it into regions x regions
+// areas for the density. Births and Deaths are left for the caller, who knows the previous turn.
+func CalculateStats(world [][]byte, width, height, regions int) Stats {
+	return NewStatsTracker(world, width, height, regions).Stats(world, 1)
+}
+
+// StatsTracker keeps the counts behind Stats up to date as cells flip, so that measuring a world
+// only has to count its components rather than scan every cell for the rest.
+type StatsTracker struct {
+	width, height, regions int
+	alive                  int
+	// rows and columns are the number of alive cells in each row and column, for the bounding box.
+	rows, columns []int
+	counts        [][]int
+}
+
+// NewStatsTracker counts the alive cells of a world of 0 and 255 valued cells, dividing it into
+// regions x regions areas for the density.
+func NewStatsTracker(world [][]byte, width, height, regions int) *StatsTracker {
+	if regions <= 0 {
+		regions = DefaultRegions
+	}
+	t := &StatsTracker{
+		width:   width,
+		height:  height,
+		regions: regions,
+		rows:    make([]int, height),
+		columns: make([]int, width),
+		counts:  make([][]int, regions),
+	}
+	for j := range t.counts {
+		t.counts[j] = make([]int, regions)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if world[y][x] == 255 {
+				t.Flip(Cell{X: x, Y: y}, true)
+			}
+		}
+	}
+	return t
+}
+
+// Flip records that cell has become alive or died.
+func (t *StatsTracker) Flip(cell Cell, alive bool) {
+	change := -1
+	if alive {
+		change = 1
+	}
+	t.alive += change
+	t.rows[cell.Y] += change
+	t.columns[cell.X] += change
+	t.counts[cell.Y*t.regions/t.height][cell.X*t.regions/t.width] += change
+}
+
+// Stats returns the stats of world, which must be the world the tracker has followed, counting its
+// components with up to threads goroutines.
+func (t *StatsTracker) Stats(world [][]byte, threads int) Stats {
+	stats := Stats{Alive: t.alive, Density: make([][]float64, t.regions)}
+	if t.alive > 0 {
+		stats.Min.X, stats.Max.X = span(t.columns)
+		stats.Min.Y, stats.Max.Y = span(t.rows)
+	}
+	for j := range t.counts {
+		stats.Density[j] = make([]float64, t.regions)
+		for i := range t.counts[j] {
+			area := ((j+1)*t.height/t.regions - j*t.height/t.regions) * ((i+1)*t.width/t.regions - i*t.width/t.regions)
+			if area > 0 {
+				stats.Density[j][i] = float64(t.counts[j][i]) / float64(area)
+			}
+		}
+	}
+	if t.alive > 0 {
+		stats.Components = countComponents(world, t.width, t.height, threads)
+	}
+	return stats
+}
+
+// span returns the first and last indices of counts that aren't zero.
+func span(counts []int) (first, last int) {
+	for first = 0; counts[first] == 0; first++ {
+	}
+	for last = len(counts) - 1; counts[last] == 0; last-- {
+	}
+	return first, last
+}
+
+// countComponents counts the 8-connected groups of alive cells on the torus. The world is split
+// into strips of rows which are labelled in parallel with a union-find, after which the cells
+// either side of each boundary between strips are joined.
+func countComponents(world [][]byte, width, height, threads int) int {
+	if threads > height {
+		threads = height
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	parent := make([]int32, width*height)
+	for i := range parent {
+		parent[i] = int32(i)
+	}
+	find := func(i int32) int32 {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	union := func(a, b int32) {
+		if a, b = find(a), find(b); a != b {
+			if a < b {
+				a, b = b, a
+			}
+			parent[a] = b
+		}
+	}
+	// join unions every alive cell in row y with the alive cells below it in row next.
+	join := func(y, next int) {
+		for x := 0; x < width; x++ {
+			if world[y][x] != 255 {
+				continue
+			}
+			for dx := -1; dx <= 1; dx++ {
+				nx := (x + dx + width) % width
+				if world[next][nx] == 255 {
+					union(int32(y*width+x), int32(next*width+nx))
+				}
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		start, end := i*height/threads, (i+1)*height/threads
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := start; y < end; y++ {
+				for x := 0; x < width; x++ {
+					if world[y][x] == 255 && world[y][(x+1)%width] == 255 {
+						union(int32(y*width+x), int32(y*width+(x+1)%width))
+					}
+				}
+				if y+1 < end {
+					join(y, y+1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < threads; i++ {
+		end := (i + 1) * height / threads
+		join(end-1, end%height)
+	}
+
+	components := 0
+	for i, p := range parent {
+		if p == int32(i) && world[i/width][i%width] == 255 {
+			components++
+		}
+	}
+	return components
+}
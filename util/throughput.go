@@ -0,0 +1,116 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ThroughputWindow is roughly how far back a Throughput remembers. Turns completed this long ago
+// carry about a third of the weight of the latest ones.
+const ThroughputWindow = 3 * time.Second
+
+// Throughput estimates the number of turns completed per second from the turns completed by
+// successive times. The estimate is an exponential moving average weighted by the time between
+// updates, so irregular and sub-second intervals are measured as accurately as regular ones.
+type Throughput struct {
+	mutex sync.Mutex
+	// cells is the number of cells updated each turn and turns is the number of turns in the run.
+	cells, turns int
+	lastTurn     int
+	lastTime     time.Time
+	rate         float64
+	measured     bool
+}
+
+// NewThroughput starts measuring a run of turns turns of a world of cells cells from now.
+// If turns isn't positive no ETA is given.
+func NewThroughput(cells, turns int) *Throughput {
+	return &Throughput{cells: cells, turns: turns, lastTime: time.Now()}
+}
+
+// Update records that completedTurns turns have been completed by now and returns the estimated
+// turns per second.
+func (t *Throughput) Update(completedTurns int) float64 {
+	return t.UpdateAt(completedTurns, time.Now())
+}
+
+// UpdateAt records that completedTurns turns had been completed by now and returns the estimated
+// turns per second. Turns going backwards, as when a new run starts, restart the estimate.
+func (t *Throughput) UpdateAt(completedTurns int, now time.Time) float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if completedTurns < t.lastTurn {
+		t.start(completedTurns, now)
+		return 0
+	}
+	elapsed := now.Sub(t.lastTime)
+	if elapsed <= 0 {
+		return t.rate
+	}
+	rate := float64(completedTurns-t.lastTurn) / elapsed.Seconds()
+	if t.measured {
+		weight := 1 - math.Exp(-elapsed.Seconds()/ThroughputWindow.Seconds())
+		t.rate += weight * (rate - t.rate)
+	} else {
+		t.rate, t.measured = rate, true
+	}
+	t.lastTurn, t.lastTime = completedTurns, now
+	return t.rate
+}
+
+// Start restarts the estimate from completedTurns turns at now, forgetting earlier updates.
+func (t *Throughput) Start(completedTurns int, now time.Time) {
+	t.mutex.Lock()
+	t.start(completedTurns, now)
+	t.mutex.Unlock()
+}
+
+func (t *Throughput) start(completedTurns int, now time.Time) {
+	t.lastTurn, t.lastTime, t.rate, t.measured = completedTurns, now, 0, false
+}
+
+// TurnsPerSecond returns the estimated turns per second, which is 0 until the first update.
+func (t *Throughput) TurnsPerSecond() float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.rate
+}
+
+// CellsPerSecond returns the estimated number of cells updated per second.
+func (t *Throughput) CellsPerSecond() float64 {
+	return t.TurnsPerSecond() * float64(t.cells)
+}
+
+// ETA returns the estimated time until every turn has been completed. It returns false if the number
+// of turns isn't known or nothing has been completed to estimate from.
+func (t *Throughput) ETA() (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.turns <= 0 || t.rate <= 0 {
+		return 0, false
+	}
+	remaining := math.Max(float64(t.turns-t.lastTurn), 0)
+	return time.Duration(remaining / t.rate * float64(time.Second)).Round(time.Second), true
+}
+
+// String describes the estimate, such as "Avg 1234.5 turns/sec 80.9M cells/sec ETA 1m2s".
+func (t *Throughput) String() string {
+	s := fmt.Sprintf("Avg %.1f turns/sec %v cells/sec", t.TurnsPerSecond(), FormatSI(t.CellsPerSecond()))
+	if eta, ok := t.ETA(); ok {
+		s += fmt.Sprintf(" ETA %v", eta)
+	}
+	return s
+}
+
+// FormatSI formats v to three significant figures with an SI prefix, such as 1.23k or 45.6M.
+func FormatSI(v float64) string {
+	for _, prefix := range []string{"", "k", "M", "G", "T"} {
+		if math.Abs(v) < 999.5 || prefix == "T" {
+			return fmt.Sprintf("%.3g%v", v, prefix)
+		}
+		v /= 1000
+	}
+	return ""
+}
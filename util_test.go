@@ -169,7 +169,7 @@ func (tester *Tester) Loop() {
 	defer clearPixels()
 	limitedAssert := LimitedAssert{t: tester.t, failed: false, limitHit: false}
 
-	avgTurns := util.NewAvgTurns()
+	throughput := util.NewThroughput(tester.params.ImageWidth*tester.params.ImageHeight, tester.params.Turns)
 
 	for {
 		select {
@@ -225,7 +225,8 @@ func (tester *Tester) Loop() {
 					<-tester.sdlSync
 				}
 			case gol.AliveCellsCount:
-				fmt.Printf("Completed Turns %-8v %-20v Avg%+5v turns/sec\n", event.GetCompletedTurns(), event, avgTurns.Get(event.GetCompletedTurns()))
+				throughput.Update(event.GetCompletedTurns())
+				fmt.Printf("Completed Turns %-8v %-20v %v\n", event.GetCompletedTurns(), event, throughput)
 			case gol.ImageOutputComplete:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 				tester.HandleEvent(e)
@@ -0,0 +1,278 @@
+// Package web streams a run to browsers over WebSockets, so that it can be watched without SDL.
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// FPS is the most frames per second sent to each browser. The cells flipped in between are sent
+// together, so a browser only receives the difference between the frames it is shown.
+const FPS = 20
+
+// forwarded are the keys passed on from browsers to the engine, which are the same as those of the SDL window.
+const forwarded = "psqkcrnb[]0123456789"
+
+//go:embed index.html
+var page []byte
+
+// hello is the first message sent to each browser, with what it needs to draw the world.
+type hello struct {
+	Type          string
+	Width, Height int
+	Rule          string
+}
+
+// maxPending is the most messages a browser can fall behind by before it is disconnected. A
+// browser that reconnects starts again from the latest frame.
+const maxPending = 10 * FPS
+
+// Server keeps the latest state of a run and serves it to any number of browsers: the page at /
+// and a WebSocket at /ws. Every event other than cell flips is sent to browsers as it happens,
+// encoded by gol.MarshalEvent, while the flipped cells are sent as a CellsFlipped followed by a
+// TurnComplete at most FPS times a second. Each frame is encoded once and shared by every browser.
+// Keys pressed in a browser are forwarded to keyPresses.
+type Server struct {
+	p          gol.Params
+	keyPresses chan<- rune
+	mutex      sync.Mutex
+	// shown and shownTurn are the state of the run as of the last frame sent to browsers, while
+	// flipped and turn are the cells flipped and the latest turn since then. A cell flipped twice
+	// between frames is removed from flipped, as it hasn't changed.
+	shown     []bool
+	shownTurn int
+	flipped   map[util.Cell]bool
+	turn      int
+	state     []byte
+	// queued are the events since the last frame, which are sent after it.
+	queued  [][]byte
+	clients map[*client]bool
+	done    chan struct{}
+}
+
+// client is a single browser, with the messages that have yet to be sent to it. wake is signalled
+// when there are more, and dropped is closed if it falls too far behind.
+type client struct {
+	conn    *Conn
+	pending [][]byte
+	wake    chan struct{}
+	dropped chan struct{}
+}
+
+func NewServer(p gol.Params, keyPresses chan<- rune) *Server {
+	if p.Rule == "" {
+		p.Rule = gol.DefaultRule
+	}
+	return &Server{
+		p:          p,
+		keyPresses: keyPresses,
+		shown:      make([]bool, p.ImageWidth*p.ImageHeight),
+		flipped:    make(map[util.Cell]bool),
+		clients:    make(map[*client]bool),
+		done:       make(chan struct{}),
+	}
+}
+
+// ListenAndServe serves a run on addr, such as ":8080", until the listener fails.
+func ListenAndServe(addr string, p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) error {
+	s := NewServer(p, keyPresses)
+	go s.Run(events)
+	return http.ListenAndServe(addr, s)
+}
+
+// Run follows the events of the run and sends frames to browsers until the channel is closed,
+// after which browsers are sent the last frame and disconnected.
+func (s *Server) Run(events <-chan gol.Event) {
+	ticker := time.NewTicker(time.Second / FPS)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mutex.Lock()
+			s.broadcast()
+			s.mutex.Unlock()
+		case event, ok := <-events:
+			s.mutex.Lock()
+			if !ok {
+				s.broadcast()
+				s.mutex.Unlock()
+				close(s.done)
+				return
+			}
+			switch e := event.(type) {
+			case gol.CellFlipped:
+				s.flip(e.Cell)
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					s.flip(cell)
+				}
+			case gol.TurnComplete:
+				s.turn = e.CompletedTurns
+			default:
+				if message, err := gol.MarshalEvent(event); err == nil {
+					if _, ok := event.(gol.StateChange); ok {
+						s.state = message
+					}
+					s.queued = append(s.queued, message)
+				}
+			}
+			s.mutex.Unlock()
+		}
+	}
+}
+
+func (s *Server) flip(cell util.Cell) {
+	if s.flipped[cell] {
+		delete(s.flipped, cell)
+	} else {
+		s.flipped[cell] = true
+	}
+}
+
+// broadcast encodes the cells flipped and the turn completed since the last frame, if there are
+// any, and queues them for every browser followed by the events since the last frame.
+// s.mutex must be held.
+func (s *Server) broadcast() {
+	if len(s.flipped) == 0 && s.turn == s.shownTurn && len(s.queued) == 0 {
+		return
+	}
+	flipped := make([]util.Cell, 0, len(s.flipped))
+	for cell := range s.flipped {
+		i := cell.Y*s.p.ImageWidth + cell.X
+		s.shown[i] = !s.shown[i]
+		flipped = append(flipped, cell)
+	}
+	s.flipped = make(map[util.Cell]bool)
+	var messages [][]byte
+	if len(flipped) > 0 {
+		message, _ := gol.MarshalEvent(gol.CellsFlipped{CompletedTurns: s.turn, Cells: flipped})
+		messages = append(messages, message)
+	}
+	if len(flipped) > 0 || s.turn != s.shownTurn {
+		message, _ := gol.MarshalEvent(gol.TurnComplete{CompletedTurns: s.turn})
+		messages = append(messages, message)
+		s.shownTurn = s.turn
+	}
+	messages = append(messages, s.queued...)
+	s.queued = nil
+	for c := range s.clients {
+		if len(c.pending)+len(messages) > maxPending {
+			c.pending = nil
+			close(c.dropped)
+			delete(s.clients, c)
+			continue
+		}
+		c.pending = append(c.pending, messages...)
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	case "/ws":
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		s.serve(conn)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serve sends frames to a browser and forwards its key presses until either end closes.
+// The browser is first sent the world as of the last frame, which later frames build on.
+func (s *Server) serve(conn *Conn) {
+	defer conn.Close()
+	c := &client{conn: conn, wake: make(chan struct{}, 1), dropped: make(chan struct{})}
+	greeting, _ := json.Marshal(hello{"Hello", s.p.ImageWidth, s.p.ImageHeight, s.p.Rule})
+	if conn.WriteText(greeting) != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	var alive []util.Cell
+	for i, cell := range s.shown {
+		if cell {
+			alive = append(alive, util.Cell{X: i % s.p.ImageWidth, Y: i / s.p.ImageWidth})
+		}
+	}
+	if len(alive) > 0 {
+		message, _ := gol.MarshalEvent(gol.CellsFlipped{CompletedTurns: s.shownTurn, Cells: alive})
+		c.pending = append(c.pending, message)
+	}
+	message, _ := gol.MarshalEvent(gol.TurnComplete{CompletedTurns: s.shownTurn})
+	c.pending = append(c.pending, message)
+	if s.state != nil {
+		c.pending = append(c.pending, s.state)
+	}
+	c.wake <- struct{}{}
+	s.clients[c] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, c)
+		s.mutex.Unlock()
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if key := []rune(string(message)); len(key) == 1 && strings.ContainsRune(forwarded, key[0]) {
+				select {
+				case s.keyPresses <- key[0]:
+				case <-s.done:
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-c.wake:
+			if s.send(c) != nil {
+				return
+			}
+		case <-s.done:
+			_ = s.send(c)
+			return
+		case <-closed:
+			return
+		case <-c.dropped:
+			return
+		}
+	}
+}
+
+// send sends the client every message pending for it.
+func (s *Server) send(c *client) error {
+	s.mutex.Lock()
+	pending := c.pending
+	c.pending = nil
+	s.mutex.Unlock()
+	for _, message := range pending {
+		if err := c.conn.WriteText(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
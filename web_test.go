@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+	"uk.ac.bris.cs/gameoflife/web"
+)
+
+// TestWeb tests that the web viewer streams cell flips and turns over a WebSocket and forwards key presses.
+func TestWeb(t *testing.T) {
+	p := gol.Params{ImageWidth: 8, ImageHeight: 8}
+	events := make(chan gol.Event, 10)
+	keyPresses := make(chan rune, 10)
+	s := web.NewServer(p, keyPresses)
+	go s.Run(events)
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+
+	conn, err := web.Dial("ws://" + strings.TrimPrefix(httpServer.URL, "http://") + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	type message struct {
+		Type           string
+		Width, Height  int
+		CompletedTurns int
+		Cells          []util.Cell
+		NewState       string
+	}
+	// next returns the next message other than a TurnComplete for turn 0, which may be sent at any time.
+	next := func() message {
+		for {
+			data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m message
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Fatalf("Cannot decode %q: %v", data, err)
+			}
+			if m.Type != "TurnComplete" || m.CompletedTurns != 0 {
+				return m
+			}
+		}
+	}
+
+	m := next()
+	assert(t, m.Type == "Hello" && m.Width == 8 && m.Height == 8, "Expected a Hello for an 8x8 world, got %+v", m)
+
+	blinker := []util.Cell{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}
+	events <- gol.CellsFlipped{CompletedTurns: 1, Cells: blinker}
+	events <- gol.TurnComplete{CompletedTurns: 1}
+	m = next()
+	assert(t, m.Type == "CellsFlipped", "Expected CellsFlipped, got %+v", m)
+	assert(t, len(m.Cells) == 3, "Expected the 3 cells of the blinker, got %v", m.Cells)
+	m = next()
+	assert(t, m.Type == "TurnComplete" && m.CompletedTurns == 1, "Expected TurnComplete for turn 1, got %+v", m)
+
+	// A cell flipped twice between frames is not sent, as it hasn't changed.
+	events <- gol.CellsFlipped{CompletedTurns: 2, Cells: []util.Cell{{X: 5, Y: 5}, {X: 1, Y: 0}, {X: 5, Y: 5}}}
+	events <- gol.TurnComplete{CompletedTurns: 2}
+	m = next()
+	assert(t, m.Type == "CellsFlipped" && reflect.DeepEqual(m.Cells, []util.Cell{{X: 1, Y: 0}}),
+		"Expected only the cell flipped once to be sent, got %+v", m)
+	// The frame may have been sent before turn 2 completed.
+	for m = next(); m.Type == "TurnComplete" && m.CompletedTurns == 1; m = next() {
+	}
+	assert(t, m.Type == "TurnComplete" && m.CompletedTurns == 2, "Expected TurnComplete for turn 2, got %+v", m)
+
+	events <- gol.StateChange{CompletedTurns: 2, NewState: gol.Paused}
+	m = next()
+	assert(t, m.Type == "StateChange" && m.NewState == "Paused", "Expected a StateChange to Paused, got %+v", m)
+
+	if err := conn.WriteText([]byte("p")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteText([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case key := <-keyPresses:
+		assert(t, key == 'p', "Expected p to be forwarded, got %q", key)
+	case <-time.After(2 * time.Second):
+		t.Fatal("No key was forwarded in 2 seconds")
+	}
+
+	// The connection is closed once the run has finished.
+	close(events)
+	for {
+		if _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	assert(t, len(keyPresses) == 0, "Only p should have been forwarded, got %v more", len(keyPresses))
+}
+
+// TestWebOrigin tests that WebSocket handshakes from pages on other sites are refused.
+func TestWebOrigin(t *testing.T) {
+	events := make(chan gol.Event)
+	defer close(events)
+	s := web.NewServer(gol.Params{ImageWidth: 8, ImageHeight: 8}, make(chan rune))
+	go s.Run(events)
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+	host := strings.TrimPrefix(httpServer.URL, "http://")
+
+	for origin, expected := range map[string]int{
+		"":                     http.StatusSwitchingProtocols,
+		"http://" + host:       http.StatusSwitchingProtocols,
+		"http://evil.example":  http.StatusForbidden,
+		"https://evil.example": http.StatusForbidden,
+	} {
+		request, err := http.NewRequest("GET", httpServer.URL+"/ws", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Connection", "Upgrade")
+		request.Header.Set("Upgrade", "websocket")
+		request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		request.Header.Set("Sec-WebSocket-Version", "13")
+		if origin != "" {
+			request.Header.Set("Origin", origin)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		assert(t, response.StatusCode == expected, "Origin %q: expected status %v, got %v", origin, expected, response.StatusCode)
+	}
+}
+
+// TestWebLateClient tests that a browser connecting after cells have flipped is sent the world so
+// far, and then follows the same frames as a browser that was already connected.
+func TestWebLateClient(t *testing.T) {
+	events := make(chan gol.Event, 10)
+	s := web.NewServer(gol.Params{ImageWidth: 8, ImageHeight: 8}, make(chan rune))
+	go s.Run(events)
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+	url := "ws://" + strings.TrimPrefix(httpServer.URL, "http://") + "/ws"
+
+	type message struct {
+		Type           string
+		CompletedTurns int
+		Cells          []util.Cell
+	}
+	// follow applies the cells flipped for a browser to alive until it is sent turn.
+	follow := func(conn *web.Conn, alive map[util.Cell]bool, turn int) {
+		for {
+			data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m message
+			_ = json.Unmarshal(data, &m)
+			switch m.Type {
+			case "CellsFlipped":
+				for _, cell := range m.Cells {
+					if alive[cell] {
+						delete(alive, cell)
+					} else {
+						alive[cell] = true
+					}
+				}
+			case "TurnComplete":
+				if m.CompletedTurns == turn {
+					return
+				}
+			}
+		}
+	}
+
+	early, err := web.Dial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer early.Close()
+	earlyCells := make(map[util.Cell]bool)
+	events <- gol.CellsFlipped{CompletedTurns: 0, Cells: []util.Cell{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}}
+	events <- gol.TurnComplete{CompletedTurns: 1}
+	follow(early, earlyCells, 1)
+
+	late, err := web.Dial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer late.Close()
+	lateCells := make(map[util.Cell]bool)
+	follow(late, lateCells, 1)
+	events <- gol.CellsFlipped{CompletedTurns: 1, Cells: []util.Cell{{X: 0, Y: 1}, {X: 2, Y: 1}, {X: 1, Y: 0}, {X: 1, Y: 2}}}
+	events <- gol.TurnComplete{CompletedTurns: 2}
+	follow(early, earlyCells, 2)
+	follow(late, lateCells, 2)
+
+	expected := map[util.Cell]bool{{X: 0, Y: 1}: true, {X: 1, Y: 1}: true, {X: 2, Y: 1}: true}
+	assert(t, reflect.DeepEqual(earlyCells, expected), "The early browser has %v alive, expected %v", earlyCells, expected)
+	assert(t, reflect.DeepEqual(lateCells, expected), "The late browser has %v alive, expected %v", lateCells, expected)
+	close(events)
+}